@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// abortInterval is how often an aborted build is polled while waiting for it to stop
+var abortInterval = 2 * time.Second
+
+// abortSignals are the ways to stop a build, in order of escalation. Pipelines that ignore stop can be
+// terminated, and then killed outright, which skips any cleanup.
+var abortSignals = []string{"stop", "term", "kill"}
+
+// abortBuild stops a running build after asking confirm whether to proceed, and waits up to grace for it to finish.
+// With escalate, a build still running after the grace period is sent the next of abortSignals.
+func abortBuild(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string, escalate bool, grace time.Duration, confirm func(*buildDetail) (bool, error)) (*buildDetail, error) {
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+	detail := newBuildDetail(build)
+	if !detail.Building {
+		return nil, fmt.Errorf("build #%d is not running (%s)", detail.Number, detail.Result)
+	}
+	if ok, err := confirm(detail); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("abort of build #%d cancelled", detail.Number)
+	}
+
+	signals := abortSignals[:1]
+	if escalate {
+		signals = abortSignals
+	}
+	for _, signal := range signals {
+		if err := signalBuild(ctx, client, build, signal); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Sent %s to build #%d\n", signal, detail.Number)
+
+		waitCtx, cancel := context.WithTimeout(ctx, grace)
+		finished, err := waitForBuild(waitCtx, build, abortInterval)
+		cancel()
+		if err == nil {
+			return finished, nil
+		}
+		if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	if !escalate {
+		return nil, fmt.Errorf("build #%d is still running after stop (escalate to term and kill with --escalate)", detail.Number)
+	}
+	return nil, fmt.Errorf("build #%d is still running after kill", detail.Number)
+}
+
+// signalBuild posts one of abortSignals to a build
+func signalBuild(ctx context.Context, client *gojenkins.Jenkins, build *gojenkins.Build, signal string) error {
+	resp, err := client.Requester.Post(ctx, build.Base+"/"+signal, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to %s build: %w", signal, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to %s build: %w", signal, newStatusError(resp))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// newAbortTestJenkins serves build #7 of app, which keeps running until it receives stopAt, recording the signals it receives
+func newAbortTestJenkins(t *testing.T, stopAt string, signals *[]string) *gojenkins.Jenkins {
+	t.Helper()
+	stopped := false
+	handlers := map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			if stopped {
+				w.Write([]byte(`{"number":7,"result":"ABORTED","building":false}`))
+				return
+			}
+			w.Write([]byte(`{"number":7,"building":true}`))
+		},
+	}
+	for _, signal := range abortSignals {
+		handlers["/job/app/7/"+signal] = func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected POST, got %s", r.Method)
+			}
+			*signals = append(*signals, signal)
+			if signal == stopAt {
+				stopped = true
+			}
+		}
+	}
+	return newTestJenkins(t, handlers)
+}
+
+func confirmed(*buildDetail) (bool, error) { return true, nil }
+
+// TestAbortBuild tests that a build that stops when asked is only sent stop
+func TestAbortBuild(t *testing.T) {
+	oldInterval := abortInterval
+	abortInterval = time.Millisecond
+	defer func() { abortInterval = oldInterval }()
+
+	var signals []string
+	client := newAbortTestJenkins(t, "stop", &signals)
+
+	build, err := abortBuild(context.Background(), client, "app", "7", true, time.Second, confirmed)
+	if err != nil {
+		t.Fatalf("abortBuild returned error: %v", err)
+	}
+	if build.Result != "ABORTED" {
+		t.Errorf("Expected ABORTED build, got %+v", build)
+	}
+	if strings.Join(signals, ",") != "stop" {
+		t.Errorf("Expected only stop, got %v", signals)
+	}
+}
+
+// TestAbortBuild_Escalate tests that a build ignoring stop and term is killed when escalating
+func TestAbortBuild_Escalate(t *testing.T) {
+	oldInterval := abortInterval
+	abortInterval = time.Millisecond
+	defer func() { abortInterval = oldInterval }()
+
+	var signals []string
+	client := newAbortTestJenkins(t, "kill", &signals)
+
+	build, err := abortBuild(context.Background(), client, "app", "7", true, 20*time.Millisecond, confirmed)
+	if err != nil {
+		t.Fatalf("abortBuild returned error: %v", err)
+	}
+	if build.Result != "ABORTED" {
+		t.Errorf("Expected ABORTED build, got %+v", build)
+	}
+	if strings.Join(signals, ",") != "stop,term,kill" {
+		t.Errorf("Expected stop, term and kill, got %v", signals)
+	}
+}
+
+// TestAbortBuild_NoEscalate tests that a build ignoring stop is reported as still running without escalating
+func TestAbortBuild_NoEscalate(t *testing.T) {
+	oldInterval := abortInterval
+	abortInterval = time.Millisecond
+	defer func() { abortInterval = oldInterval }()
+
+	var signals []string
+	client := newAbortTestJenkins(t, "kill", &signals)
+
+	_, err := abortBuild(context.Background(), client, "app", "7", false, 20*time.Millisecond, confirmed)
+	if err == nil || !strings.Contains(err.Error(), "--escalate") {
+		t.Errorf("Expected still running error suggesting --escalate, got: %v", err)
+	}
+	if strings.Join(signals, ",") != "stop" {
+		t.Errorf("Expected only stop, got %v", signals)
+	}
+}
+
+// TestAbortBuild_NotConfirmed tests that nothing is sent to the build unless the abort is confirmed
+func TestAbortBuild_NotConfirmed(t *testing.T) {
+	var signals []string
+	client := newAbortTestJenkins(t, "stop", &signals)
+
+	declined := func(*buildDetail) (bool, error) { return false, nil }
+	_, err := abortBuild(context.Background(), client, "app", "7", false, time.Second, declined)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancelled error, got: %v", err)
+	}
+	if len(signals) != 0 {
+		t.Errorf("Expected no signals, got %v", signals)
+	}
+}
+
+// TestRun_AbortBuildRejected verifies that abort-build is rejected unless write access is enabled
+func TestRun_AbortBuildRejected(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	err := run(context.Background(), []string{"abort-build", "app", "7", "--yes"})
+	if err == nil || !strings.Contains(err.Error(), "requires write access") {
+		t.Errorf("Expected 'requires write access' error, got: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// jobPath returns the API path of a job, given its name as used on the command line
+func jobPath(jobName string) string {
+	return "/job/" + jobURLName(jobName)
+}
+
+// jobURLName converts a full job path like "folder/name" to the "folder/job/name" form used in Jenkins URLs.
+// Names already in that form are returned unchanged.
+func jobURLName(jobName string) string {
+	name := strings.Trim(jobName, "/")
+	if strings.Contains(name, "/job/") {
+		return name
+	}
+	return strings.ReplaceAll(name, "/", "/job/")
+}
+
+// lookupJob gets a job by its name as used on the command line
+func lookupJob(ctx context.Context, client *gojenkins.Jenkins, jobName string) (*gojenkins.Job, error) {
+	job, err := client.GetJob(ctx, jobURLName(jobName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// getJSON gets the JSON API of a Jenkins path (e.g. "/job/app", without "api/json") into v.
+// Unlike the gojenkins requester, it reports non-200 responses as errors.
+func getJSON(ctx context.Context, client *gojenkins.Jenkins, path string, v any, query map[string]string) error {
+	resp, err := client.Requester.GetJSON(ctx, path, v, query)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp)
+	}
+	return nil
+}
+
+// jobNameFromURL returns the full path of a job, like "folder/name", from its URL, or "" if it is not a job URL
+func jobNameFromURL(jobURL string) string {
+	_, rest, ok := strings.Cut(jobURL, "/job/")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(strings.ReplaceAll(rest, "/job/", "/"), "/")
+}
+
+// getRawJSON gets JSON from a Jenkins path that is not under api/json, like "/job/app/7/wfapi/describe", into v.
+// Like getJSON, it reports non-200 responses as errors.
+func getRawJSON(ctx context.Context, client *gojenkins.Jenkins, path string, v any) error {
+	resp, err := client.Requester.Get(ctx, path, v, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp)
+	}
+	return nil
+}
+
+// openStream sends a GET request for a Jenkins path, which may include a query string, with the client's HTTP client
+// and credentials. Unlike the gojenkins requester, it returns the response without reading the body, so that the
+// caller can stream it; the caller must close the body.
+func openStream(ctx context.Context, client *gojenkins.Jenkins, path string, header http.Header) (*http.Response, error) {
+	return sendRequest(ctx, client, http.MethodGet, path, header)
+}
+
+// sendRequest sends a request for a Jenkins path like openStream, with any method
+func sendRequest(ctx context.Context, client *gojenkins.Jenkins, method, path string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, client.Requester.Base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if auth := client.Requester.BasicAuth; auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return client.Requester.Client.Do(req)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	"github.com/dustin/go-humanize"
+)
+
+// progressInterval is how often download progress is redrawn on a terminal
+const progressInterval = 200 * time.Millisecond
+
+// artifact is a file archived by a build
+type artifact struct {
+	// Path is the path of the artifact relative to the build's archive
+	Path     string `json:"path"`
+	FileName string `json:"fileName"`
+	URL      string `json:"url"`
+}
+
+// artifactList is the result of listing the artifacts of a build
+type artifactList struct {
+	Job       string     `json:"job"`
+	Number    int64      `json:"number"`
+	Artifacts []artifact `json:"artifacts"`
+}
+
+// downloadedFile is an artifact written to disk
+type downloadedFile struct {
+	Path string `json:"path"`
+	// File is where the artifact was written
+	File string `json:"file"`
+	Size int64  `json:"size"`
+}
+
+// downloadResult is the result of downloading the artifacts of a build
+type downloadResult struct {
+	Job    string           `json:"job"`
+	Number int64            `json:"number"`
+	Dest   string           `json:"dest"`
+	Files  []downloadedFile `json:"files"`
+}
+
+func (l *artifactList) writeText(w io.Writer) {
+	if len(l.Artifacts) == 0 {
+		fmt.Fprintln(w, "No artifacts found")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d artifact(s):\n\n", len(l.Artifacts))
+	for _, a := range l.Artifacts {
+		fmt.Fprintf(w, "%-50s %s\n", a.Path, a.URL)
+	}
+}
+
+func (r *downloadResult) writeText(w io.Writer) {
+	var total int64
+	for _, f := range r.Files {
+		total += f.Size
+	}
+	fmt.Fprintf(w, "Downloaded %d artifact(s) of build #%d to %s (%s)\n", len(r.Files), r.Number, r.Dest, humanize.Bytes(uint64(total)))
+}
+
+// fetchArtifacts lists the artifacts of a build whose path matches glob, or all of them if it is empty.
+// A glob without "/" is matched against the file name, otherwise against the whole path.
+func fetchArtifacts(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber, glob string) (*artifactList, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob: %s", glob)
+	}
+
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &artifactList{Job: jobName, Number: build.GetBuildNumber(), Artifacts: []artifact{}}
+	for _, a := range build.Raw.Artifacts {
+		if glob != "" {
+			name := a.RelativePath
+			if !strings.Contains(glob, "/") {
+				name = a.FileName
+			}
+			if ok, _ := path.Match(glob, name); !ok {
+				continue
+			}
+		}
+		list.Artifacts = append(list.Artifacts, artifact{
+			Path:     a.RelativePath,
+			FileName: a.FileName,
+			URL:      strings.TrimSuffix(build.GetUrl(), "/") + "/artifact/" + escapePath(a.RelativePath),
+		})
+	}
+	return list, nil
+}
+
+// downloadArtifacts downloads the artifacts of a build matching glob into dest, keeping their relative paths,
+// with up to concurrency downloads at once. Each file is written to a ".part" file first and renamed once complete
+// and of the expected size, so an interrupted download never leaves a truncated artifact, and is resumed from where
+// it stopped when the same build's artifacts are downloaded again.
+// With progress, the overall progress is redrawn on stderr.
+func downloadArtifacts(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber, glob, dest string, concurrency int, progress bool) (*downloadResult, error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive: %d", concurrency)
+	}
+	list, err := fetchArtifacts(ctx, client, jobName, buildNumber, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &downloadResult{Job: jobName, Number: list.Number, Dest: dest, Files: make([]downloadedFile, len(list.Artifacts))}
+	if len(list.Artifacts) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var downloaded, done atomic.Int64
+	stopProgress := func() {}
+	if progress {
+		stopProgress = reportProgress(&downloaded, &done, len(list.Artifacts))
+	}
+
+	base := jobPath(jobName) + "/" + strconv.FormatInt(list.Number, 10) + "/artifact/"
+	partSuffix := buildPartSuffix(jobName, list.Number)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i, a := range list.Artifacts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			file, err := downloadArtifact(ctx, client, base+escapePath(a.Path), dest, a.Path, partSuffix, &downloaded)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to download %s: %w", a.Path, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			result.Files[i] = *file
+			done.Add(1)
+			if !progress {
+				fmt.Fprintf(os.Stderr, "Downloaded %s (%s)\n", file.Path, humanize.Bytes(uint64(file.Size)))
+			}
+		}()
+	}
+	wg.Wait()
+	stopProgress()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// downloadArtifact downloads one artifact to its relative path under dest, resuming a previous partial download
+// of the same build, and adds the bytes received to downloaded as they arrive. The partial download is kept in a
+// ".part" file named after the build by partSuffix, so that one left by another build is never resumed.
+func downloadArtifact(ctx context.Context, client *gojenkins.Jenkins, artifactPath, dest, relativePath, partSuffix string, downloaded *atomic.Int64) (*downloadedFile, error) {
+	target := filepath.Join(dest, filepath.FromSlash(relativePath))
+	if rel, err := filepath.Rel(dest, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("artifact path is outside the destination directory")
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+
+	partPath := target + "." + partSuffix + ".part"
+	part, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+	offset, err := part.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, total, err := requestArtifact(ctx, client, artifactPath, offset)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.StatusCode == http.StatusOK {
+		// The partial download does not fit the artifact, or the server ignored the range, so start again
+		if err := part.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := part.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		offset = 0
+	}
+	if resp == nil {
+		if resp, total, err = requestArtifact(ctx, client, artifactPath, 0); err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("the server did not send the artifact from its start")
+		}
+	}
+	defer resp.Body.Close()
+	downloaded.Add(offset)
+
+	n, err := io.Copy(part, &countingReader{r: resp.Body, n: downloaded})
+	if err != nil {
+		return nil, err
+	}
+	size := offset + n
+	if total >= 0 && size != total {
+		if size > total {
+			// Never resume from a file larger than the artifact
+			os.Remove(partPath)
+		}
+		return nil, fmt.Errorf("incomplete download: got %d of %d bytes", size, total)
+	}
+	if err := part.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partPath, target); err != nil {
+		return nil, err
+	}
+	return &downloadedFile{Path: relativePath, File: target, Size: size}, nil
+}
+
+// requestArtifact requests an artifact from offset, returning the response and the size of the whole artifact,
+// or -1 if it is not known. It returns no response if the download cannot be resumed from offset: when the range
+// is past the end of the artifact, or the partial response does not start at offset.
+func requestArtifact(ctx context.Context, client *gojenkins.Jenkins, artifactPath string, offset int64) (*http.Response, int64, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := openStream(ctx, client, artifactPath, header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, resp.ContentLength, nil
+	case http.StatusPartialContent:
+		// e.g. "bytes 1000-5999/6000", or "bytes 1000-5999/*" if the size is not known
+		var start, end int64
+		var size string
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &size); err != nil || start != offset {
+			resp.Body.Close()
+			return nil, 0, nil
+		}
+		total, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			total = -1
+		}
+		return resp, total, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, 0, nil
+	default:
+		resp.Body.Close()
+		return nil, 0, newStatusError(resp)
+	}
+}
+
+// buildPartSuffix names the partial downloads of a build, so that they are only resumed by the same build.
+// The job name is hashed, as it may contain slashes.
+func buildPartSuffix(jobName string, number int64) string {
+	sum := sha256.Sum256([]byte(jobURLName(jobName)))
+	return fmt.Sprintf("%x-%d", sum[:4], number)
+}
+
+// countingReader adds the number of bytes read to n
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// reportProgress redraws the download progress on stderr until the returned function is called
+func reportProgress(downloaded, done *atomic.Int64, total int) func() {
+	draw := func() {
+		fmt.Fprintf(os.Stderr, "\rDownloading %d/%d artifact(s), %s  ", done.Load(), total, humanize.Bytes(uint64(downloaded.Load())))
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				draw()
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		draw()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// escapePath escapes each segment of a slash-separated path for use in a URL
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = neturl.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
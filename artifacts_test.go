@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// testArtifacts are the artifacts served by newArtifactsTestJenkins, by relative path
+var testArtifacts = map[string]string{
+	"dist/app.tar.gz":    strings.Repeat("binary", 1000),
+	"dist/app.zip":       "zip",
+	"reports/junit.xml":  "<testsuites/>",
+	"reports/a b/c.html": "<html/>",
+}
+
+// newArtifactsTestJenkins serves build #7 of app with testArtifacts, recording the Range headers received
+func newArtifactsTestJenkins(t *testing.T, ranges *[]string) *gojenkins.Jenkins {
+	t.Helper()
+	return newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"url":"http://jenkins/job/app/7/","result":"SUCCESS","artifacts":[` +
+				`{"fileName":"app.tar.gz","relativePath":"dist/app.tar.gz"},` +
+				`{"fileName":"app.zip","relativePath":"dist/app.zip"},` +
+				`{"fileName":"junit.xml","relativePath":"reports/junit.xml"},` +
+				`{"fileName":"c.html","relativePath":"reports/a b/c.html"}]}`))
+		},
+		"/job/app/7/artifact/": func(w http.ResponseWriter, r *http.Request) {
+			if _, _, ok := r.BasicAuth(); !ok {
+				t.Error("Expected credentials on artifact request")
+			}
+			if ranges != nil && r.Header.Get("Range") != "" {
+				*ranges = append(*ranges, r.Header.Get("Range"))
+			}
+			content, ok := testArtifacts[strings.TrimPrefix(r.URL.Path, "/job/app/7/artifact/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+		},
+	})
+}
+
+// TestFetchArtifacts tests listing artifacts, filtered by a glob on the file name or the whole path
+func TestFetchArtifacts(t *testing.T) {
+	client := newArtifactsTestJenkins(t, nil)
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{"", []string{"dist/app.tar.gz", "dist/app.zip", "reports/junit.xml", "reports/a b/c.html"}},
+		{"*.tar.gz", []string{"dist/app.tar.gz"}},
+		{"reports/*", []string{"reports/junit.xml"}},
+		{"app.*", []string{"dist/app.tar.gz", "dist/app.zip"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			list, err := fetchArtifacts(context.Background(), client, "app", "7", tt.glob)
+			if err != nil {
+				t.Fatalf("fetchArtifacts returned error: %v", err)
+			}
+			var paths []string
+			for _, a := range list.Artifacts {
+				paths = append(paths, a.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, paths)
+			}
+		})
+	}
+
+	list, err := fetchArtifacts(context.Background(), client, "app", "7", "*.html")
+	if err != nil {
+		t.Fatalf("fetchArtifacts returned error: %v", err)
+	}
+	if want := "http://jenkins/job/app/7/artifact/reports/a%20b/c.html"; list.Artifacts[0].URL != want {
+		t.Errorf("Expected URL %s, got %s", want, list.Artifacts[0].URL)
+	}
+}
+
+// TestDownloadArtifacts tests that artifacts are downloaded concurrently to their relative paths
+func TestDownloadArtifacts(t *testing.T) {
+	client := newArtifactsTestJenkins(t, nil)
+	dest := t.TempDir()
+
+	result, err := downloadArtifacts(context.Background(), client, "app", "7", "", dest, 2, false)
+	if err != nil {
+		t.Fatalf("downloadArtifacts returned error: %v", err)
+	}
+	if len(result.Files) != len(testArtifacts) {
+		t.Errorf("Expected %d files, got %d", len(testArtifacts), len(result.Files))
+	}
+	for relativePath, content := range testArtifacts {
+		data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(relativePath)))
+		if err != nil {
+			t.Errorf("Failed to read %s: %v", relativePath, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", relativePath, data)
+		}
+	}
+
+	var buf bytes.Buffer
+	result.writeText(&buf)
+	if !strings.Contains(buf.String(), "Downloaded 4 artifact(s) of build #7") {
+		t.Errorf("Unexpected text output: %s", buf.String())
+	}
+}
+
+// TestDownloadArtifacts_Resume tests that a partial download is resumed from where it stopped
+func TestDownloadArtifacts_Resume(t *testing.T) {
+	var ranges []string
+	client := newArtifactsTestJenkins(t, &ranges)
+	dest := t.TempDir()
+
+	content := testArtifacts["dist/app.tar.gz"]
+	part := filepath.Join(dest, "dist", "app.tar.gz."+buildPartSuffix("app", 7)+".part")
+	if err := os.MkdirAll(filepath.Dir(part), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(part, []byte(content[:1000]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := downloadArtifacts(context.Background(), client, "app", "7", "*.tar.gz", dest, 1, false)
+	if err != nil {
+		t.Fatalf("downloadArtifacts returned error: %v", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=1000-" {
+		t.Errorf("Expected a single range request from byte 1000, got %v", ranges)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "dist", "app.tar.gz"))
+	if err != nil || string(data) != content {
+		t.Errorf("Expected complete artifact, got %d bytes (%v)", len(data), err)
+	}
+	if result.Files[0].Size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), result.Files[0].Size)
+	}
+	if _, err := os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("Expected the .part file to be renamed, got: %v", err)
+	}
+}
+
+// TestDownloadArtifacts_StalePart tests that partial downloads of other builds are not resumed, and that one that
+// does not fit the artifact is downloaded again
+func TestDownloadArtifacts_StalePart(t *testing.T) {
+	var ranges []string
+	client := newArtifactsTestJenkins(t, &ranges)
+	dest := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dest, "dist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := map[string]string{
+		// Another build's download of app.zip
+		"app.zip." + buildPartSuffix("app", 6) + ".part":   "zip of build 6",
+		"app.zip." + buildPartSuffix("other", 7) + ".part": "zip of another job",
+		// Longer than app.tar.gz, so the range is not satisfiable
+		"app.tar.gz." + buildPartSuffix("app", 7) + ".part": strings.Repeat("x", len(testArtifacts["dist/app.tar.gz"])+100),
+	}
+	for name, content := range stale {
+		if err := os.WriteFile(filepath.Join(dest, "dist", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := downloadArtifacts(context.Background(), client, "app", "7", "app.*", dest, 1, false); err != nil {
+		t.Fatalf("downloadArtifacts returned error: %v", err)
+	}
+	for _, relativePath := range []string{"dist/app.zip", "dist/app.tar.gz"} {
+		data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(relativePath)))
+		if err != nil || string(data) != testArtifacts[relativePath] {
+			t.Errorf("Expected %s to be downloaded afresh, got %q (%v)", relativePath, data, err)
+		}
+	}
+	if len(ranges) != 1 {
+		t.Errorf("Expected only the download of this build's app.tar.gz to be resumed, got ranges %v", ranges)
+	}
+}
+
+// TestDownloadArtifact_MisplacedRange tests that a partial response not starting where the download stopped is not
+// appended to it
+func TestDownloadArtifact_MisplacedRange(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/artifact/": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				w.Header().Set("Content-Range", "bytes 0-2/3")
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			w.Write([]byte("zip"))
+		},
+	})
+	dest := t.TempDir()
+	suffix := buildPartSuffix("app", 7)
+	if err := os.WriteFile(filepath.Join(dest, "app.zip."+suffix+".part"), []byte("z"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var downloaded atomic.Int64
+	file, err := downloadArtifact(context.Background(), client, "/artifact/app.zip", dest, "app.zip", suffix, &downloaded)
+	if err != nil {
+		t.Fatalf("downloadArtifact returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(file.File); string(data) != "zip" {
+		t.Errorf("Expected the artifact downloaded again, got %q", data)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// buildPermalinks are the symbolic build references accepted wherever a build number is, keyed by lower-case name
+var buildPermalinks = map[string]func(*gojenkins.JobResponse) gojenkins.JobBuild{
+	"last":           func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastBuild },
+	"lastsuccessful": func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastSuccessfulBuild },
+	"lastfailed":     func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastFailedBuild },
+	"laststable":     func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastStableBuild },
+	"lastunstable":   func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastUnstableBuild },
+	"lastcompleted":  func(j *gojenkins.JobResponse) gojenkins.JobBuild { return j.LastCompletedBuild },
+}
+
+// jobBuildsLimit is the most builds Jenkins lists in a job's builds; older builds are only listed in allBuilds
+const jobBuildsLimit = 100
+
+// resolveBuildNumber resolves a build reference to a build number using the job's permalinks and build history.
+// A reference is a build number, a permalink such as "last" or "lastFailed" (the Jenkins names like
+// "lastFailedBuild" are also accepted), optionally followed by "~N" to select the Nth build before it.
+// When that build is older than the builds Jenkins lists with the job, allBuilds is called for the full history.
+func resolveBuildNumber(job *gojenkins.JobResponse, ref string, allBuilds func() ([]gojenkins.JobBuild, error)) (int64, error) {
+	base, back, relative := strings.Cut(ref, "~")
+	steps := 0
+	if relative {
+		var err error
+		steps, err = strconv.Atoi(back)
+		if err != nil || steps < 0 {
+			return 0, fmt.Errorf("invalid build number: %s", ref)
+		}
+	}
+
+	var number int64
+	if permalink, ok := buildPermalinks[strings.TrimSuffix(strings.ToLower(base), "build")]; ok {
+		number = permalink(job).Number
+		if number == 0 {
+			return 0, fmt.Errorf("job has no %s build", base)
+		}
+	} else {
+		var err error
+		number, err = parseBuildNumber(base)
+		if err != nil {
+			return 0, fmt.Errorf("invalid build number: %s (use a number, or one of last, lastSuccessful, lastFailed, lastStable, lastUnstable, lastCompleted, optionally followed by ~N)", ref)
+		}
+	}
+	if steps == 0 {
+		return number, nil
+	}
+
+	builds := job.Builds
+	previous, ok := buildBefore(builds, number, steps)
+	if !ok && len(builds) >= jobBuildsLimit && allBuilds != nil {
+		var err error
+		if builds, err = allBuilds(); err != nil {
+			return 0, err
+		}
+		previous, ok = buildBefore(builds, number, steps)
+	}
+	if ok {
+		return previous, nil
+	}
+	if slices.ContainsFunc(builds, func(b gojenkins.JobBuild) bool { return b.Number == number }) {
+		return 0, notFoundErrorf("build %s not found in the job's build history", ref)
+	}
+	return 0, notFoundErrorf("build #%d not found in the job's build history", number)
+}
+
+// buildBefore returns the build steps builds before number in a build history, which is newest first and may have
+// gaps where builds were deleted
+func buildBefore(builds []gojenkins.JobBuild, number int64, steps int) (int64, bool) {
+	for i, build := range builds {
+		if build.Number == number && i+steps < len(builds) {
+			return builds[i+steps].Number, true
+		}
+	}
+	return 0, false
+}
+
+// fetchAllBuilds returns the numbers of all the builds of a job, newest first, rather than only the latest
+// jobBuildsLimit
+func fetchAllBuilds(ctx context.Context, client *gojenkins.Jenkins, jobName string) ([]gojenkins.JobBuild, error) {
+	var history struct {
+		AllBuilds []gojenkins.JobBuild `json:"allBuilds"`
+	}
+	if err := getJSON(ctx, client, jobPath(jobName), &history, map[string]string{"tree": "allBuilds[number]"}); err != nil {
+		return nil, fmt.Errorf("failed to get build history: %w", err)
+	}
+	return history.AllBuilds, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bndr/gojenkins"
+)
+
+// TestResolveBuildNumber tests resolving build numbers and symbolic references
+func TestResolveBuildNumber(t *testing.T) {
+	job := &gojenkins.JobResponse{
+		Builds:              []gojenkins.JobBuild{{Number: 10}, {Number: 9}, {Number: 7}, {Number: 6}},
+		LastBuild:           gojenkins.JobBuild{Number: 10},
+		LastSuccessfulBuild: gojenkins.JobBuild{Number: 9},
+		LastFailedBuild:     gojenkins.JobBuild{Number: 7},
+		LastCompletedBuild:  gojenkins.JobBuild{Number: 9},
+	}
+
+	tests := []struct {
+		ref      string
+		expected int64
+	}{
+		{"42", 42},
+		{"last", 10},
+		{"lastBuild", 10},
+		{"lastSuccessful", 9},
+		{"lastSuccessfulBuild", 9},
+		{"lastfailed", 7},
+		{"lastCompleted", 9},
+		{"last~0", 10},
+		{"last~2", 7},
+		{"lastSuccessful~1", 7},
+		{"9~2", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			number, err := resolveBuildNumber(job, tt.ref, nil)
+			if err != nil {
+				t.Fatalf("resolveBuildNumber(%q) returned error: %v", tt.ref, err)
+			}
+			if number != tt.expected {
+				t.Errorf("resolveBuildNumber(%q) = %d, want %d", tt.ref, number, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResolveBuildNumber_Invalid tests that unresolvable references are rejected
+func TestResolveBuildNumber_Invalid(t *testing.T) {
+	job := &gojenkins.JobResponse{
+		Builds:    []gojenkins.JobBuild{{Number: 2}, {Number: 1}},
+		LastBuild: gojenkins.JobBuild{Number: 2},
+	}
+
+	for _, ref := range []string{"0", "-1", "latest", "lastStable", "last~2", "last~x", "last~-1", "5~1"} {
+		t.Run(ref, func(t *testing.T) {
+			if _, err := resolveBuildNumber(job, ref, nil); err == nil {
+				t.Errorf("resolveBuildNumber(%q) expected error, got nil", ref)
+			}
+		})
+	}
+}
+
+// TestLookupBuild_BeyondBuilds tests that ~N reaches builds older than the latest 100 that Jenkins lists with a job
+func TestLookupBuild_BeyondBuilds(t *testing.T) {
+	var builds, allBuilds []string
+	for n := 150; n >= 1; n-- {
+		if n > 50 {
+			builds = append(builds, fmt.Sprintf(`{"number":%d}`, n))
+		}
+		allBuilds = append(allBuilds, fmt.Sprintf(`{"number":%d}`, n))
+	}
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("tree") == "allBuilds[number]" {
+				fmt.Fprintf(w, `{"allBuilds":[%s]}`, strings.Join(allBuilds, ","))
+				return
+			}
+			fmt.Fprintf(w, `{"name":"app","lastBuild":{"number":150},"builds":[%s]}`, strings.Join(builds, ","))
+		},
+		"/job/app/30/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":30,"result":"SUCCESS"}`))
+		},
+	})
+
+	build, err := lookupBuild(context.Background(), client, "app", "last~120")
+	if err != nil {
+		t.Fatalf("lookupBuild returned error: %v", err)
+	}
+	if build.GetBuildNumber() != 30 {
+		t.Errorf("lookupBuild(last~120) = #%d, want #30", build.GetBuildNumber())
+	}
+	if _, err := lookupBuild(context.Background(), client, "app", "last~150"); classifyError(err) != kindNotFound {
+		t.Errorf("lookupBuild(last~150) = %v, want a not found error", err)
+	}
+}
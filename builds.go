@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// buildPageSize is how many builds are fetched per request when listing builds
+const buildPageSize = 50
+
+// buildHistoryTree is the tree query selecting the build fields needed to list and filter builds
+const buildHistoryTree = "number,url,result,building,timestamp,duration," +
+	"actions[causes[userId,userName,shortDescription],lastBuiltRevision[branch[name]]]"
+
+// buildSummary is a build as it appears in a listing
+type buildSummary struct {
+	Number   int64  `json:"number"`
+	URL      string `json:"url"`
+	Result   string `json:"result,omitempty"`
+	Building bool   `json:"building"`
+	// Timestamp is when the build started
+	Timestamp time.Time `json:"timestamp,omitzero"`
+	// Duration is the build duration in milliseconds, as reported by Jenkins
+	Duration  float64  `json:"duration,omitempty"`
+	Branches  []string `json:"branches,omitempty"`
+	StartedBy []string `json:"startedBy,omitempty"`
+
+	// users are the IDs and names of the users who started the build, and causes the descriptions of its other
+	// causes, which --started-by matches
+	users  []string
+	causes []string
+}
+
+// buildList is the result of listing the builds of a job
+type buildList struct {
+	Job    string         `json:"job"`
+	Builds []buildSummary `json:"builds"`
+}
+
+// Status returns the build result, or BUILDING while the build is running
+func (b *buildSummary) Status() string {
+	if b.Building {
+		return "BUILDING"
+	}
+	return b.Result
+}
+
+func (l *buildList) writeText(w io.Writer) {
+	if len(l.Builds) == 0 {
+		fmt.Fprintln(w, "No builds found")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d build(s):\n\n", len(l.Builds))
+	for _, build := range l.Builds {
+		started := ""
+		if !build.Timestamp.IsZero() {
+			started = build.Timestamp.Format("2006-01-02 15:04")
+		}
+		duration := ""
+		if build.Duration > 0 {
+			duration = formatDuration(build.Duration)
+		}
+		fmt.Fprintf(w, "#%-7d %-10s %-17s %-12s %s\n", build.Number, build.Status(), started, duration, strings.Join(build.StartedBy, ", "))
+	}
+}
+
+// buildFilter selects the builds returned by fetchBuildList
+type buildFilter struct {
+	// Limit is the maximum number of builds to return
+	Limit int
+	// Results are the accepted results (e.g. FAILURE, or BUILDING for running builds); empty accepts any
+	Results []string
+	// Since excludes builds started before it, unless zero
+	Since time.Time
+	// Branch selects builds of a branch, as recorded by the Git plugin
+	Branch string
+	// StartedBy selects builds started by the user with this ID or name, or with a cause other than a user whose
+	// description contains it (e.g. "timer")
+	StartedBy string
+}
+
+// newBuildFilter parses the build filter options shared by the CLI and the MCP server.
+// result is a comma-separated list of results, and since is a duration such as "24h" or "7d".
+func newBuildFilter(limit int, result, since, branch, startedBy string) (buildFilter, error) {
+	if limit <= 0 {
+		return buildFilter{}, fmt.Errorf("limit must be positive: %d", limit)
+	}
+	filter := buildFilter{Limit: limit, Results: parseStatusList(result), Branch: branch, StartedBy: startedBy}
+	if since != "" {
+		d, err := parseSince(since)
+		if err != nil {
+			return buildFilter{}, err
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	return filter, nil
+}
+
+// parseStatusList parses a comma-separated list of results or statuses, e.g. "failure,UNSTABLE"
+func parseStatusList(s string) []string {
+	var statuses []string
+	for _, status := range strings.Split(s, ",") {
+		if status = strings.ToUpper(strings.TrimSpace(status)); status != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// parseSince parses a duration such as "90m", "24h" or "7d"
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid duration: %s (e.g. 90m, 24h or 7d)", s)
+}
+
+// buildHistoryEntry is a build as returned by the tree query
+type buildHistoryEntry struct {
+	Number    int64   `json:"number"`
+	URL       string  `json:"url"`
+	Result    string  `json:"result"`
+	Building  bool    `json:"building"`
+	Timestamp int64   `json:"timestamp"`
+	Duration  float64 `json:"duration"`
+	Actions   []struct {
+		Causes []struct {
+			UserID           string `json:"userId"`
+			UserName         string `json:"userName"`
+			ShortDescription string `json:"shortDescription"`
+		} `json:"causes"`
+		LastBuiltRevision struct {
+			Branch []struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"lastBuiltRevision"`
+	} `json:"actions"`
+}
+
+// fetchBuildList lists the builds of a job, newest first, that match the filter.
+// Builds are fetched a page at a time using allBuilds{from,to} (builds is capped at the latest 100 by Jenkins),
+// stopping as soon as enough builds match or the builds become older than filter.Since.
+func fetchBuildList(ctx context.Context, client *gojenkins.Jenkins, jobName string, filter buildFilter) (*buildList, error) {
+	list := &buildList{Job: jobName, Builds: []buildSummary{}}
+	for from := 0; ; from += buildPageSize {
+		var page struct {
+			AllBuilds []buildHistoryEntry `json:"allBuilds"`
+		}
+		tree := fmt.Sprintf("allBuilds[%s]{%d,%d}", buildHistoryTree, from, from+buildPageSize)
+		if err := getJSON(ctx, client, jobPath(jobName), &page, map[string]string{"tree": tree}); err != nil {
+			return nil, fmt.Errorf("failed to list builds: %w", err)
+		}
+
+		for _, entry := range page.AllBuilds {
+			build := newBuildSummary(entry)
+			if !filter.Since.IsZero() && !build.Timestamp.IsZero() && build.Timestamp.Before(filter.Since) {
+				return list, nil
+			}
+			if !filter.matches(build) {
+				continue
+			}
+			list.Builds = append(list.Builds, build)
+			if len(list.Builds) >= filter.Limit {
+				return list, nil
+			}
+		}
+		if len(page.AllBuilds) < buildPageSize {
+			return list, nil
+		}
+	}
+}
+
+func newBuildSummary(entry buildHistoryEntry) buildSummary {
+	build := buildSummary{
+		Number:   entry.Number,
+		URL:      entry.URL,
+		Result:   entry.Result,
+		Building: entry.Building,
+		Duration: entry.Duration,
+	}
+	if entry.Timestamp != 0 {
+		build.Timestamp = time.UnixMilli(entry.Timestamp)
+	}
+	for _, action := range entry.Actions {
+		for _, branch := range action.LastBuiltRevision.Branch {
+			build.Branches = appendUnique(build.Branches, branch.Name)
+		}
+		for _, cause := range action.Causes {
+			if cause.UserID != "" {
+				build.users = appendUnique(build.users, cause.UserID)
+			}
+			if cause.UserName != "" {
+				build.users = appendUnique(build.users, cause.UserName)
+			}
+			if cause.UserID == "" && cause.UserName == "" && cause.ShortDescription != "" {
+				build.causes = appendUnique(build.causes, cause.ShortDescription)
+			}
+			switch {
+			case cause.UserName != "":
+				build.StartedBy = appendUnique(build.StartedBy, cause.UserName)
+			case cause.UserID != "":
+				build.StartedBy = appendUnique(build.StartedBy, cause.UserID)
+			case cause.ShortDescription != "":
+				build.StartedBy = appendUnique(build.StartedBy, cause.ShortDescription)
+			}
+		}
+	}
+	return build
+}
+
+func (f buildFilter) matches(build buildSummary) bool {
+	if len(f.Results) > 0 && !slices.Contains(f.Results, build.Status()) {
+		return false
+	}
+	if f.Branch != "" && !matchesBranch(build.Branches, f.Branch) {
+		return false
+	}
+	if f.StartedBy != "" && !matchesStartedBy(build, f.StartedBy) {
+		return false
+	}
+	return true
+}
+
+// matchesBranch reports whether any branch is the given branch, ignoring ref and remote prefixes like "refs/remotes/origin/"
+func matchesBranch(branches []string, branch string) bool {
+	for _, b := range branches {
+		if b == branch || strings.HasSuffix(b, "/"+branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesStartedBy reports whether the build was started by the user with this ID or name, or has a cause other
+// than a user whose description contains the text, ignoring case. Users are matched exactly, so that "bob" does
+// not match "bobby".
+func matchesStartedBy(build buildSummary, startedBy string) bool {
+	for _, user := range build.users {
+		if strings.EqualFold(user, startedBy) {
+			return true
+		}
+	}
+	startedBy = strings.ToLower(startedBy)
+	for _, cause := range build.causes {
+		if strings.Contains(strings.ToLower(cause), startedBy) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFetchBuildList tests that builds are paged and filtered
+func TestFetchBuildList(t *testing.T) {
+	now := time.Now()
+	var trees []string
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			tree := r.URL.Query().Get("tree")
+			trees = append(trees, tree)
+			var from, to int
+			fmt.Sscanf(tree[strings.LastIndex(tree, "{"):], "{%d,%d}", &from, &to)
+			// 120 builds, numbered 120 down to 1, started an hour apart, every third one failed
+			builds := []map[string]any{}
+			for i := from; i < to && i < 120; i++ {
+				number := 120 - i
+				result := "SUCCESS"
+				if number%3 == 0 {
+					result = "FAILURE"
+				}
+				builds = append(builds, map[string]any{
+					"number":    number,
+					"result":    result,
+					"timestamp": now.Add(-time.Duration(i) * time.Hour).UnixMilli(),
+					"actions": []map[string]any{
+						{"causes": []map[string]any{{"userId": "alice", "userName": "Alice", "shortDescription": "Started by user Alice"}}},
+						{"lastBuiltRevision": map[string]any{"branch": []map[string]any{{"name": "refs/remotes/origin/main"}}}},
+					},
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"allBuilds": builds})
+		},
+	})
+
+	filter, err := newBuildFilter(25, "failure", "", "main", "alice")
+	if err != nil {
+		t.Fatalf("newBuildFilter returned error: %v", err)
+	}
+	list, err := fetchBuildList(context.Background(), client, "app", filter)
+	if err != nil {
+		t.Fatalf("fetchBuildList returned error: %v", err)
+	}
+	if len(list.Builds) != 25 || list.Builds[0].Number != 120 || list.Builds[24].Number != 48 {
+		t.Errorf("Expected 25 failed builds from #120 to #48, got %d", len(list.Builds))
+	}
+	if len(trees) != 2 || !strings.HasSuffix(trees[0], "{0,50}") || !strings.HasSuffix(trees[1], "{50,100}") {
+		t.Errorf("Expected two pages of builds, got tree queries %q", trees)
+	}
+
+	trees = nil
+	filter, err = newBuildFilter(100, "", "10h", "", "")
+	if err != nil {
+		t.Fatalf("newBuildFilter returned error: %v", err)
+	}
+	list, err = fetchBuildList(context.Background(), client, "app", filter)
+	if err != nil {
+		t.Fatalf("fetchBuildList returned error: %v", err)
+	}
+	if len(list.Builds) != 10 || len(trees) != 1 {
+		t.Errorf("Expected 10 builds from a single page, got %d builds from %d pages", len(list.Builds), len(trees))
+	}
+
+	filter, err = newBuildFilter(5, "", "", "develop", "")
+	if err != nil {
+		t.Fatalf("newBuildFilter returned error: %v", err)
+	}
+	list, err = fetchBuildList(context.Background(), client, "app", filter)
+	if err != nil {
+		t.Fatalf("fetchBuildList returned error: %v", err)
+	}
+	if len(list.Builds) != 0 {
+		t.Errorf("Expected no builds of branch develop, got %d", len(list.Builds))
+	}
+}
+
+// TestListBuildsHandler tests the list_builds tool, with its flags as parameters
+func TestListBuildsHandler(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"allBuilds":[
+				{"number":3,"result":"FAILURE","actions":[{"causes":[{"userId":"alice","userName":"Alice"}]}]},
+				{"number":2,"result":"SUCCESS","actions":[{"causes":[{"userId":"bob","userName":"Bob"}]}]},
+				{"number":1,"result":"FAILURE","actions":[{"causes":[{"userId":"bob","userName":"Bob"}]}]}
+			]}`))
+		},
+	})
+
+	result := callTestTool(t, client, "list_builds", map[string]any{"job_name": "app", "result": "FAILURE", "started_by": "bob"})
+	if result.IsError {
+		t.Fatalf("list_builds failed: %+v", result)
+	}
+	list, ok := result.StructuredContent.(*buildList)
+	if !ok || list.Job != "app" || len(list.Builds) != 1 || list.Builds[0].Number != 1 {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	if result := callTestTool(t, client, "list_builds", map[string]any{"job_name": "app", "since": "yesterday"}); !result.IsError {
+		t.Errorf("Expected an error for an invalid since, got %+v", result)
+	}
+}
+
+// TestMatchesStartedBy tests that users are matched by exact ID or name, and other causes by their description
+func TestMatchesStartedBy(t *testing.T) {
+	var entry buildHistoryEntry
+	if err := json.Unmarshal([]byte(`{"actions": [{"causes": [
+		{"userId": "bobby", "userName": "Bobby Tables", "shortDescription": "Started by user Bobby Tables"},
+		{"shortDescription": "Started by timer"}
+	]}]}`), &entry); err != nil {
+		t.Fatal(err)
+	}
+	build := newBuildSummary(entry)
+
+	tests := map[string]bool{
+		"bobby":        true,
+		"BOBBY":        true,
+		"Bobby Tables": true,
+		"bob":          false,
+		"Tables":       false,
+		"user":         false,
+		"timer":        true,
+		"by TIMER":     true,
+	}
+	for startedBy, want := range tests {
+		if got := matchesStartedBy(build, startedBy); got != want {
+			t.Errorf("matchesStartedBy(%q) = %v, want %v", startedBy, got, want)
+		}
+	}
+}
+
+// TestNewBuildFilter_Invalid tests that invalid filter options are rejected
+func TestNewBuildFilter_Invalid(t *testing.T) {
+	if _, err := newBuildFilter(0, "", "", "", ""); err == nil {
+		t.Error("Expected error for zero limit, got nil")
+	}
+	for _, since := range []string{"yesterday", "-1h", "xd"} {
+		if _, err := newBuildFilter(10, "", since, "", ""); err == nil {
+			t.Errorf("Expected error for since %q, got nil", since)
+		}
+	}
+}
+
+// TestParseSince tests parsing durations with day support
+func TestParseSince(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90m": 90 * time.Minute,
+		"24h": 24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+	}
+	for s, expected := range tests {
+		d, err := parseSince(s)
+		if err != nil || d != expected {
+			t.Errorf("parseSince(%q) = %v, %v, want %v", s, d, err, expected)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	"github.com/kitproj/jenkins-cli/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// command is a sub-command of the CLI, declaring everything needed to run it, describe it in the usage and help,
+// and expose it as an MCP tool
+type command struct {
+	// name is what the command is run as, which may be two words, like "config view"
+	name string
+	args []argument
+	// summary is the one-line description shown in the usage
+	summary string
+	// description is shown after the summary by 'jenkins help <command>'
+	description string
+	// write marks commands that change Jenkins state, which require write access
+	write bool
+	// run runs a command without flags
+	run runFunc
+	// setup registers the command's flags and returns the function running it, for commands with flags
+	setup func(fs *flag.FlagSet) runFunc
+	// tool exposes the command to MCP clients, if set
+	tool *mcpTool
+}
+
+// runFunc runs a command with its positional arguments, of which there are at least as many as it requires
+type runFunc func(ctx context.Context, args []string, p printer) error
+
+// argument is a positional argument of a command. Required arguments are also required string parameters
+// of the command's MCP tool.
+type argument struct {
+	name        string
+	description string
+	optional    bool
+}
+
+// usage returns the argument as shown in the usage, e.g. <job-name> or [username]
+func (a argument) usage() string {
+	if a.optional {
+		return "[" + a.name + "]"
+	}
+	return "<" + a.name + ">"
+}
+
+// param returns the name of the argument as an MCP tool parameter, e.g. job_name
+func (a argument) param() string {
+	return paramName(a.name)
+}
+
+// paramName returns the name of an MCP tool parameter for an argument or flag, e.g. started_by for started-by
+func paramName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// mcpTool is the MCP tool of a command. Its parameters are the command's required arguments and its flags, with
+// the flags' descriptions and defaults, so that the CLI and the tool cannot drift apart.
+type mcpTool struct {
+	name        string
+	description string
+	// cliOnly are the flags of the command that the tool does not take, e.g. those about exit statuses
+	cliOnly []string
+	// options are the tool's parameters that are not flags of the command
+	options []mcp.ToolOption
+	handler toolHandler
+}
+
+// toolHandler handles a call of a tool, with the values of the command's flags given as parameters
+type toolHandler func(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error)
+
+// toolFlags are the values of a command's flags for a call of its tool: those given as parameters, and the
+// defaults of the others
+type toolFlags struct {
+	fs *flag.FlagSet
+}
+
+func (f toolFlags) get(name string) any {
+	return f.fs.Lookup(name).Value.(flag.Getter).Get()
+}
+
+func (f toolFlags) getBool(name string) bool {
+	return f.get(name).(bool)
+}
+
+func (f toolFlags) getInt(name string) int {
+	return f.get(name).(int)
+}
+
+func (f toolFlags) getString(name string) string {
+	return f.get(name).(string)
+}
+
+func (f toolFlags) getDuration(name string) time.Duration {
+	return f.get(name).(time.Duration)
+}
+
+// flagSet returns the command's flags, and the function running it
+func (c *command) flagSet() (*flag.FlagSet, runFunc) {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	// Errors are reported by main, followed by the command's help
+	fs.SetOutput(io.Discard)
+	if c.setup != nil {
+		return fs, c.setup(fs)
+	}
+	return fs, c.run
+}
+
+// synopsis returns how the command is run, e.g. "jenkins get-build <job-name> <build-number> [flags]"
+func (c *command) synopsis(fs *flag.FlagSet) string {
+	parts := []string{"jenkins", c.name}
+	for _, arg := range c.args {
+		parts = append(parts, arg.usage())
+	}
+	if hasFlags(fs) {
+		parts = append(parts, "[flags]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasFlags reports whether any flags are defined
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// printHelp prints the command's synopsis, description, arguments and flags
+func (c *command) printHelp(w io.Writer) {
+	fs, _ := c.flagSet()
+	fmt.Fprintf(w, "Usage: %s\n\n", c.synopsis(fs))
+	fmt.Fprintln(w, c.summary)
+	if c.description != "" {
+		fmt.Fprintf(w, "\n%s\n", c.description)
+	}
+	if c.write {
+		fmt.Fprintln(w, "\nThis command changes Jenkins state and requires write access.")
+	}
+	if len(c.args) > 0 {
+		fmt.Fprintln(w, "\nArguments:")
+		for _, arg := range c.args {
+			fmt.Fprintf(w, "  %s\n    \t%s\n", arg.usage(), arg.description)
+		}
+	}
+	if hasFlags(fs) {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+	if c.tool != nil {
+		fmt.Fprintf(w, "\nMCP tool: %s\n", c.tool.name)
+	}
+}
+
+// execute parses the command's flags and arguments and runs it, printing its help instead for -h or --help
+func (c *command) execute(ctx context.Context, args []string) error {
+	fs, run := c.flagSet()
+	addGlobalFlags(fs)
+	selected := contextName
+	rest, err := parseCommandFlags(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+	if err != nil {
+		return &commandUsageError{command: c, err: err}
+	}
+	var missing []string
+	for i, arg := range c.args {
+		if !arg.optional && i >= len(rest) {
+			missing = append(missing, arg.usage())
+		}
+	}
+	if len(missing) > 0 {
+		return &commandUsageError{command: c, err: usageErrorf("missing arguments: %s", strings.Join(missing, " "))}
+	}
+	if contextName != selected {
+		config.SelectContext(contextName)
+	}
+	p, err := newPrinter(output)
+	if err != nil {
+		return err
+	}
+	credentials = nil
+	if c.write {
+		if err := requireWriteAccess(c.name); err != nil {
+			return err
+		}
+	}
+	err = run(ctx, rest, p)
+	if err != nil && classifyError(err) == kindUsage {
+		return &commandUsageError{command: c, err: err}
+	}
+	return err
+}
+
+// addGlobalFlags adds the global flags to a command's flag set, so that they may also come after the command, except
+// those the command has a flag of the same name for
+func addGlobalFlags(fs *flag.FlagSet) {
+	globalFlags.VisitAll(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+}
+
+// newTool returns the command's MCP tool definition, with its required arguments as required string parameters,
+// followed by its flags
+func (c *command) newTool() mcp.Tool {
+	opts := []mcp.ToolOption{mcp.WithDescription(c.tool.description)}
+	for _, arg := range c.args {
+		if !arg.optional {
+			opts = append(opts, mcp.WithString(arg.param(), mcp.Required(), mcp.Description(arg.description)))
+		}
+	}
+	fs, _ := c.flagSet()
+	c.visitToolFlags(fs, func(f *flag.Flag) {
+		opts = append(opts, flagParam(f))
+	})
+	return mcp.NewTool(c.tool.name, append(opts, c.tool.options...)...)
+}
+
+// visitToolFlags visits the flags the command's tool takes: all but the shorthands and the CLI-only flags
+func (c *command) visitToolFlags(fs *flag.FlagSet, fn func(f *flag.Flag)) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Usage, "Shorthand for ") || slices.Contains(c.tool.cliOnly, f.Name) {
+			return
+		}
+		fn(f)
+	})
+}
+
+// flagReference matches a reference to a flag in a flag's description, e.g. "--grep"
+var flagReference = regexp.MustCompile(`--([a-z][a-z-]*)`)
+
+// flagParam returns the tool parameter of a flag, of the flag's type and with its default
+func flagParam(f *flag.Flag) mcp.ToolOption {
+	name := paramName(f.Name)
+	usage := flagReference.ReplaceAllStringFunc(f.Usage, func(ref string) string {
+		return paramName(ref[2:])
+	})
+	switch value := f.Value.(flag.Getter).Get().(type) {
+	case bool:
+		return mcp.WithBoolean(name, mcp.Description(usage), mcp.DefaultBool(value))
+	case int:
+		return mcp.WithNumber(name, mcp.Description(usage), mcp.DefaultNumber(float64(value)))
+	case time.Duration:
+		return mcp.WithString(name, mcp.Description(usage+", as a duration such as 30s or 5m"), mcp.DefaultString(value.String()))
+	default:
+		return mcp.WithString(name, mcp.Description(usage), mcp.DefaultString(f.DefValue))
+	}
+}
+
+// callTool calls the command's tool, setting the command's flags from the parameters given
+func (c *command) callTool(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fs, _ := c.flagSet()
+	params := request.GetArguments()
+	var err error
+	c.visitToolFlags(fs, func(f *flag.Flag) {
+		value, ok := params[paramName(f.Name)]
+		if !ok || value == nil || err != nil {
+			return
+		}
+		text := fmt.Sprint(value)
+		if number, ok := value.(float64); ok {
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		if setErr := fs.Set(f.Name, text); setErr != nil {
+			err = usageErrorf("Invalid '%s' argument: %v", paramName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return toolError(err), nil
+	}
+	return c.tool.handler(ctx, client, request, toolFlags{fs: fs})
+}
+
+// commandUsageError is a usage error of a command, for which the command's help is printed rather than the usage
+type commandUsageError struct {
+	command *command
+	err     error
+}
+
+func (e *commandUsageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandUsageError) Unwrap() error {
+	return e.err
+}
+
+// lookupCommand returns the command named by the start of args, and the arguments following its name
+func lookupCommand(args []string) (*command, []string, error) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && slices.Equal(args[:len(words)], words) {
+			return c, args[len(words):], nil
+		}
+	}
+	// The first word of a two-word command, like "config"
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, args[0]+" ") {
+			return nil, nil, &commandUsageError{command: c, err: usageErrorf("unknown sub-command: %s", strings.Join(args, " "))}
+		}
+	}
+	return nil, nil, usageErrorf("unknown sub-command: %s", args[0])
+}
+
+// printUsage prints the usage of the CLI, listing its commands and global flags
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintln(w)
+	for _, c := range commands {
+		fs, _ := c.flagSet()
+		summary := c.summary
+		if c.write {
+			summary += " (requires write access)"
+		}
+		fmt.Fprintf(w, "  %s - %s\n", c.synopsis(fs), summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'jenkins help <command>' or 'jenkins <command> -h' for the arguments and flags of a command.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "A <build-number> may also be last, lastSuccessful, lastFailed, lastStable, lastUnstable or lastCompleted,")
+	fmt.Fprintln(w, "optionally followed by ~N for the Nth build before it (e.g. last~2).")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options, which may come before the command or after it with its flags:")
+	globalFlags.SetOutput(w)
+	globalFlags.PrintDefaults()
+	globalFlags.SetOutput(nil)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit status (get-build --exit-status, get-build-log --follow, wait-build, build-job --wait or --follow):")
+	fmt.Fprintln(w, "  0 success, 1 failure, 2 unstable, 3 aborted or not built, 4 still running")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit status of other errors:")
+	fmt.Fprintln(w, "  5 invalid usage, 6 not found, 7 unauthorized, 8 forbidden, 9 network error or Jenkins unavailable, 10 any other error")
+}
+
+// help prints the help of the named command, or the usage without one
+func help(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		printUsage(w)
+		return nil
+	}
+	c, _, err := lookupCommand(args)
+	// The first word of a two-word command is enough to find it
+	if usageErr := (*commandUsageError)(nil); errors.As(err, &usageErr) {
+		c, err = usageErr.command, nil
+	}
+	if err != nil {
+		return err
+	}
+	c.printHelp(w)
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bndr/gojenkins"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// TestCommands tests that the commands and their MCP tools are declared consistently
+func TestCommands(t *testing.T) {
+	names := map[string]bool{}
+	tools := map[string]bool{}
+	for _, c := range commands {
+		if names[c.name] {
+			t.Errorf("Duplicate command %q", c.name)
+		}
+		names[c.name] = true
+		if c.summary == "" {
+			t.Errorf("Command %q has no summary", c.name)
+		}
+		if (c.run == nil) == (c.setup == nil) {
+			t.Errorf("Command %q must have exactly one of run and setup", c.name)
+		}
+		if c.tool == nil {
+			continue
+		}
+		if tools[c.tool.name] || c.tool.handler == nil {
+			t.Errorf("Tool %q of command %q is a duplicate or has no handler", c.tool.name, c.name)
+		}
+		tools[c.tool.name] = true
+	}
+	if len(tools) != 10 {
+		t.Errorf("Expected 10 MCP tools, got %d", len(tools))
+	}
+}
+
+// TestNewTool tests that a command's arguments become the required parameters of its tool
+func TestNewTool(t *testing.T) {
+	c, _, err := lookupCommand([]string{"get-build-log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := c.newTool()
+	if tool.Name != "get_build_log" {
+		t.Errorf("Name = %q, want get_build_log", tool.Name)
+	}
+	if !slices.Equal(tool.InputSchema.Required, []string{"job_name", "build_number"}) {
+		t.Errorf("Required = %v, want [job_name build_number]", tool.InputSchema.Required)
+	}
+	for _, param := range []string{"job_name", "build_number", "offset", "limit_bytes", "head", "tail", "grep", "errors", "context_lines"} {
+		if _, ok := tool.InputSchema.Properties[param]; !ok {
+			t.Errorf("Missing parameter %q", param)
+		}
+	}
+	for _, param := range []string{"follow", "f", "C"} {
+		if _, ok := tool.InputSchema.Properties[param]; ok {
+			t.Errorf("Unexpected parameter %q", param)
+		}
+	}
+	if property := tool.InputSchema.Properties["context_lines"].(map[string]any); property["default"] != 0.0 ||
+		property["description"] != "With grep or errors, also print N lines around each selected line" {
+		t.Errorf("Unexpected context_lines parameter: %v", property)
+	}
+}
+
+// TestRun_GlobalFlagsAfterCommand tests that global flags may also be given after the command
+func TestRun_GlobalFlagsAfterCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_URL", "")
+	defer func() { output, url = "text", "" }()
+
+	if err := run(context.Background(), []string{"config", "view", "-o", "json", "--url", "https://jenkins.example.com"}); err != nil {
+		t.Fatalf("run(config view -o json --url ...) = %v, want nil", err)
+	}
+	if output != "json" || url != "https://jenkins.example.com" {
+		t.Errorf("Expected the global flags to be set, got output %q and url %q", output, url)
+	}
+}
+
+// TestRun_Help tests the help of commands, and that commands used wrongly report their own usage
+func TestRun_Help(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"help"},
+		{"help", "list-builds"},
+		{"help", "config", "view"},
+		{"list-builds", "-h"},
+		{"get-build", "--help"},
+	} {
+		if err := run(ctx, args); err != nil {
+			t.Errorf("run(%q) = %v, want nil", args, err)
+		}
+	}
+
+	if err := run(ctx, []string{"help", "no-such-command"}); classifyError(err) != kindUsage {
+		t.Errorf("help for an unknown command = %v, want a usage error", err)
+	}
+
+	err := run(ctx, []string{"get-build", "app"})
+	var usageErr *commandUsageError
+	if !errors.As(err, &usageErr) || usageErr.command.name != "get-build" {
+		t.Fatalf("run(get-build app) = %v, want a usage error of get-build", err)
+	}
+	if !strings.Contains(err.Error(), "<build-number>") {
+		t.Errorf("Expected the missing argument in the error, got: %v", err)
+	}
+
+	var help strings.Builder
+	usageErr.command.printHelp(&help)
+	for _, want := range []string{"Usage: jenkins get-build <job-name> <build-number> [flags]", "-exit-status", "MCP tool: get_build"} {
+		if !strings.Contains(help.String(), want) {
+			t.Errorf("Expected %q in the help, got:\n%s", want, help.String())
+		}
+	}
+}
+
+// callTestTool calls an MCP tool as the MCP server does, with the given parameters
+func callTestTool(t *testing.T, client *gojenkins.Jenkins, tool string, params map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	for _, c := range commands {
+		if c.tool == nil || c.tool.name != tool {
+			continue
+		}
+		var request mcp.CallToolRequest
+		request.Params.Arguments = params
+		result, err := c.callTool(context.Background(), client, request)
+		if err != nil {
+			t.Fatalf("%s returned error: %v", tool, err)
+		}
+		return result
+	}
+	t.Fatalf("No tool %s", tool)
+	return nil
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Arguments shared by several commands
+var (
+	jobNameArg = argument{
+		name:        "job-name",
+		description: "Jenkins job name, or full path for jobs in folders (e.g., 'team/service/main')",
+	}
+	buildNumberArg = argument{
+		name:        "build-number",
+		description: "Build number (e.g., '42'), or a symbolic reference: last, lastSuccessful, lastFailed, lastStable, lastUnstable or lastCompleted, optionally followed by ~N for the Nth build before it (e.g., 'last~2')",
+	}
+	queueIDArg = argument{
+		name:        "id",
+		description: "Queue item id (e.g., '42') or URL (e.g., 'https://jenkins.example.com/queue/item/42/')",
+	}
+)
+
+// commands are the sub-commands of the CLI, in the order they are listed in the usage, and the source of the tools
+// of the MCP server. They are set in init as some of them refer to commands.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:        "configure",
+			args:        []argument{{name: "url", description: "Jenkins URL (e.g., 'https://jenkins.example.com')"}, {name: "username", description: "Jenkins username (default admin)", optional: true}},
+			summary:     "Configure Jenkins URL, API token, TLS and HTTP settings as a named context and switch to it, optionally enabling commands that change Jenkins state",
+			description: "The API token is read from stdin, with hidden input on a terminal, unless given by --token-file or --token-env,\nand is checked with Jenkins unless --no-verify is given. Reconfiguring a context keeps the settings whose flags are\nnot given, e.g. pass --allow-write=false to disable write access or --ca-file \"\" to stop trusting a CA file.",
+			setup: func(fs *flag.FlagSet) runFunc {
+				name := fs.String("name", "", "Name of the context to save, by default the context in use")
+				enableWrite := fs.Bool("allow-write", false, "Enable commands that change Jenkins state, such as build-job and abort-build")
+				var tlsSettings config.TLS
+				fs.StringVar(&tlsSettings.CAFile, "ca-file", "", "PEM bundle of certificate authorities to trust in addition to the system's")
+				fs.StringVar(&tlsSettings.ClientCert, "client-cert", "", "PEM client certificate to present for mutual TLS (with --client-key)")
+				fs.StringVar(&tlsSettings.ClientKey, "client-key", "", "PEM private key of the client certificate")
+				fs.BoolVar(&tlsSettings.InsecureSkipVerify, "insecure-skip-verify", false, "Do not verify the server's certificate (insecure, for testing only)")
+				var httpSettings config.HTTP
+				timeout := fs.Duration("timeout", 0, "How long to wait for Jenkins to connect and start responding (default 30s)")
+				fs.StringVar(&httpSettings.Proxy, "proxy", "", "URL of the proxy to use instead of those in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars")
+				retries := fs.Int("retries", defaultRetries, "How many times to retry a GET failing with a 502, 503 or 504 status or a connection reset")
+				tokenBackend := fs.String("token-backend", "", "Where to store or read the token: keyring, file (encrypted), netrc or command (default: keyring, or file if no keyring is available)")
+				tokenFile := fs.String("token-file", "", "Read the token from this file instead of stdin")
+				tokenEnv := fs.String("token-env", "", "Read the token from this env var instead of stdin")
+				noVerify := fs.Bool("no-verify", false, "Save the token without checking that Jenkins accepts it")
+				tokenCommand := fs.String("token-command", "", "Shell command printing the token, e.g. a password manager's (implies --token-backend command)")
+				return func(ctx context.Context, args []string, p printer) error {
+					if *timeout > 0 {
+						httpSettings.Timeout = timeout.String()
+					}
+					// Only keep the retries when given, so the default can change
+					given := map[string]bool{}
+					fs.Visit(func(f *flag.Flag) {
+						given[f.Name] = true
+						if f.Name == "retries" {
+							httpSettings.Retries = retries
+						}
+					})
+					username := ""
+					if len(args) >= 2 {
+						username = args[1]
+					}
+					if *name != "" {
+						config.SelectContext(*name)
+					}
+					return configure(ctx, args[0], username, configureOptions{
+						allowWrite:   *enableWrite,
+						tls:          tlsSettings,
+						http:         httpSettings,
+						tokenBackend: *tokenBackend,
+						tokenCommand: *tokenCommand,
+						noVerify:     *noVerify,
+						tokenFile:    *tokenFile,
+						tokenEnv:     *tokenEnv,
+						given:        given,
+					})
+				}
+			},
+		},
+		{
+			name:    "logout",
+			summary: "Delete the stored token and configuration of the context in use, or of another context",
+			setup: func(fs *flag.FlagSet) runFunc {
+				name := fs.String("context", "", "Context to log out of, by default the context in use")
+				return func(ctx context.Context, args []string, p printer) error {
+					return logout(*name)
+				}
+			},
+		},
+		{
+			name:    "whoami",
+			summary: "Show the Jenkins URL, username and token in use, where each came from, and who Jenkins authenticates them as",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return printWhoAmI(ctx, p)
+			},
+		},
+		{
+			name:    "config view",
+			summary: "Show the Jenkins URL, username, token and other settings in use and where each came from, without contacting Jenkins",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return viewConfig(p)
+			},
+		},
+		{
+			name:    "list-contexts",
+			summary: "List the configured Jenkins servers, marking the current one",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return listContexts(p)
+			},
+		},
+		{
+			name:    "use-context",
+			args:    []argument{{name: "name", description: "Name of the context to switch to"}},
+			summary: "Switch the current context to another configured Jenkins server",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return useContext(args[0])
+			},
+		},
+		{
+			name:    "list-jobs",
+			summary: "List Jenkins jobs, optionally including those in folders",
+			setup: func(fs *flag.FlagSet) runFunc {
+				recursive := fs.Bool("recursive", false, "List jobs inside folders and multi-branch pipelines by their full path")
+				fs.BoolVar(recursive, "r", false, "Shorthand for -recursive")
+				name := fs.String("name", "", "Only list jobs whose full path matches this glob (or regular expression with --regex)")
+				var filters jobFilterFlags
+				filters.register(fs)
+				return func(ctx context.Context, args []string, p printer) error {
+					opts, err := filters.options(*recursive, *name)
+					if err != nil {
+						return usageError(err)
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return listJobs(ctx, opts, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "list_jobs",
+				description: "List Jenkins jobs with their status and URL, optionally searching folders and filtering by name, status or whether they are building",
+				handler:     listJobsHandler,
+			},
+		},
+		{
+			name:    "search-jobs",
+			args:    []argument{{name: "pattern", description: "Glob (or regular expression with --regex) matched against the full path of each job; a pattern without wildcards matches any path containing it"}},
+			summary: "Search jobs in all folders by full path",
+			setup: func(fs *flag.FlagSet) runFunc {
+				var filters jobFilterFlags
+				filters.register(fs)
+				return func(ctx context.Context, args []string, p printer) error {
+					opts, err := filters.options(true, args[0])
+					if err != nil {
+						return usageError(err)
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return listJobs(ctx, opts, p)
+					})
+				}
+			},
+		},
+		{
+			name:    "get-job",
+			args:    []argument{jobNameArg},
+			summary: "Get details of a specific job",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return executeCommand(ctx, func(ctx context.Context) error {
+					return getJob(ctx, args[0], p)
+				})
+			},
+			tool: &mcpTool{
+				name:        "get_job",
+				description: "Get details of a specific Jenkins job including status, description, and build history",
+				handler:     getJobHandler,
+			},
+		},
+		{
+			name:    "list-builds",
+			args:    []argument{jobNameArg},
+			summary: "List the builds of a job, newest first",
+			setup: func(fs *flag.FlagSet) runFunc {
+				limit := fs.Int("limit", 20, "Maximum number of builds to list")
+				result := fs.String("result", "", "Only list builds with these results, comma-separated (e.g. FAILURE,UNSTABLE or BUILDING)")
+				since := fs.String("since", "", "Only list builds started within this duration (e.g. 24h or 7d)")
+				branch := fs.String("branch", "", "Only list builds of this Git branch")
+				startedBy := fs.String("started-by", "", "Only list builds started by the user with this ID or name, or with a cause containing this text (e.g. timer)")
+				return func(ctx context.Context, args []string, p printer) error {
+					filter, err := newBuildFilter(*limit, *result, *since, *branch, *startedBy)
+					if err != nil {
+						return usageError(err)
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return listBuilds(ctx, args[0], filter, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "list_builds",
+				description: "List the builds of a Jenkins job, newest first, with their status, start time, duration and cause",
+				handler:     listBuildsHandler,
+			},
+		},
+		{
+			name:    "get-build",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Get details of a specific build, optionally exiting with a status reflecting its result",
+			setup: func(fs *flag.FlagSet) runFunc {
+				exitStatus := fs.Bool("exit-status", false, "Exit with a status reflecting the build result (0 success, 1 failure, 2 unstable, 3 aborted, 4 running)")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return getBuild(ctx, args[0], args[1], *exitStatus, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "get_build",
+				description: "Get details of a specific build including status, duration, and timestamp",
+				cliOnly:     []string{"exit-status"},
+				handler:     getBuildHandler,
+			},
+		},
+		{
+			name:    "wait-build",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Wait for a build to finish and exit with a status reflecting its result",
+			setup: func(fs *flag.FlagSet) runFunc {
+				interval := fs.Duration("interval", 10*time.Second, "How often to poll the build")
+				timeout := fs.Duration("timeout", 0, "Give up waiting after this long and exit with status 4 (0 waits forever)")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return waitBuild(ctx, args[0], args[1], *interval, *timeout, p)
+					})
+				}
+			},
+		},
+		{
+			name:    "get-build-log",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Get the console output of a build, optionally streaming it until the build finishes or extracting the relevant lines",
+			setup: func(fs *flag.FlagSet) runFunc {
+				follow := fs.Bool("follow", false, "Stream the log until the build finishes, then exit with its result")
+				fs.BoolVar(follow, "f", false, "Shorthand for -follow")
+				head := fs.Int("head", 0, "Only print the first N lines")
+				tail := fs.Int("tail", 0, "Only print the last N lines")
+				grep := fs.String("grep", "", "Only print lines matching this regular expression")
+				errorsOnly := fs.Bool("errors", false, "Only print lines that look like errors: error messages, stack traces and the output of failing steps")
+				contextLines := fs.Int("context-lines", 0, "With --grep or --errors, also print N lines around each selected line")
+				fs.IntVar(contextLines, "C", 0, "Shorthand for -context-lines")
+				return func(ctx context.Context, args []string, p printer) error {
+					filter, err := newLogFilter(*head, *tail, *grep, *errorsOnly, *contextLines)
+					if err != nil {
+						return usageError(err)
+					}
+					jobName := args[0]
+					buildNumber := args[1]
+					if *follow {
+						if output != "" && output != "text" {
+							return usageErrorf("--follow only supports text output")
+						}
+						if filter.active() {
+							return usageErrorf("--follow cannot be combined with --head, --tail, --grep or --errors")
+						}
+						return executeCommand(ctx, func(ctx context.Context) error {
+							return followBuildLog(ctx, jenkins, jobName, buildNumber, os.Stdout)
+						})
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return getBuildLog(ctx, jobName, buildNumber, filter, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "get_build_log",
+				description: "Get a page of the console output of a specific build, with the offset to continue from and the total log size; use tail to read the end of the log. With grep or errors, the log is searched from offset until limit_bytes of selected lines are found (the last ones with tail)",
+				cliOnly:     []string{"follow"},
+				options: []mcp.ToolOption{
+					mcp.WithNumber("offset",
+						mcp.Description("Byte offset to start reading from, e.g. the nextOffset of a previous page; not allowed with tail alone (default 0)"),
+					),
+					mcp.WithNumber("limit_bytes",
+						mcp.Description(fmt.Sprintf("Maximum number of bytes of log to read, or of selected lines to return with head, grep or errors (default %d)", defaultLogLimit)),
+					),
+				},
+				handler: getBuildLogHandler,
+			},
+		},
+		{
+			name:    "get-stages",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Get the stages of a pipeline build with their status and duration, or the log of one stage",
+			setup: func(fs *flag.FlagSet) runFunc {
+				stageName := fs.String("stage", "", "Get the log of this stage instead of listing the stages")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return getStages(ctx, args[0], args[1], *stageName, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "get_build_stages",
+				description: "Get the stages of a pipeline build with their status, duration and pause time, to find which stage failed",
+				handler:     getBuildStagesHandler,
+			},
+		},
+		{
+			name:    "get-test-report",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Get the test results of a build with the failing tests (--output junit re-emits JUnit XML)",
+			setup: func(fs *flag.FlagSet) runFunc {
+				suite := fs.String("suite", "", "Only include test suites whose name matches this glob")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return getTestReport(ctx, args[0], args[1], *suite, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "get_test_report",
+				description: "Get the JUnit test results of a build: pass, fail and skip counts, and the failing tests with their error details",
+				handler:     getTestReportHandler,
+			},
+		},
+		{
+			name:    "list-artifacts",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "List the artifacts archived by a build",
+			setup: func(fs *flag.FlagSet) runFunc {
+				glob := fs.String("glob", "", "Only list artifacts matching this glob, against the file name or, if it contains /, the whole path")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return listArtifacts(ctx, args[0], args[1], *glob, p)
+					})
+				}
+			},
+		},
+		{
+			name:    "download-artifacts",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Download the artifacts archived by a build",
+			setup: func(fs *flag.FlagSet) runFunc {
+				glob := fs.String("glob", "", "Only download artifacts matching this glob, against the file name or, if it contains /, the whole path")
+				dest := fs.String("dest", ".", "Directory to download the artifacts into, keeping their relative paths")
+				concurrency := fs.Int("concurrency", 4, "Maximum number of artifacts downloaded at once")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return downloadBuildArtifacts(ctx, args[0], args[1], *glob, *dest, *concurrency, p)
+					})
+				}
+			},
+		},
+		{
+			name:    "list-queue",
+			summary: "List the items waiting in the build queue and why they are waiting",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return executeCommand(ctx, func(ctx context.Context) error {
+					return listQueue(ctx, p)
+				})
+			},
+			tool: &mcpTool{
+				name:        "list_queue",
+				description: "List the items waiting in the Jenkins build queue, with why each is waiting, whether it is blocked or stuck, and how long it has been queued",
+				handler:     listQueueHandler,
+			},
+		},
+		{
+			name:    "get-queue-item",
+			args:    []argument{queueIDArg},
+			summary: "Get a queue item, including why it is waiting or the build it started",
+			run: func(ctx context.Context, args []string, p printer) error {
+				id, err := parseQueueID(args[0])
+				if err != nil {
+					return usageError(err)
+				}
+				return executeCommand(ctx, func(ctx context.Context) error {
+					return getQueueItem(ctx, id, p)
+				})
+			},
+			tool: &mcpTool{
+				name:        "get_queue_item",
+				description: "Get a queue item, including why it is waiting or, once it has left the queue, the build it started",
+				handler:     getQueueItemHandler,
+			},
+		},
+		{
+			name:    "wait-queue-item",
+			args:    []argument{queueIDArg},
+			summary: "Wait for a queue item to start a build",
+			setup: func(fs *flag.FlagSet) runFunc {
+				interval := fs.Duration("interval", 10*time.Second, "How often to poll the queue item")
+				timeout := fs.Duration("timeout", 5*time.Minute, "Give up waiting after this long (0 waits forever)")
+				return func(ctx context.Context, args []string, p printer) error {
+					id, err := parseQueueID(args[0])
+					if err != nil {
+						return usageError(err)
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return waitQueueItem(ctx, id, *interval, *timeout, p)
+					})
+				}
+			},
+			tool: &mcpTool{
+				name:        "wait_queue_item",
+				description: "Wait for a queue item to leave the queue and return the build it started; if the timeout is reached first, the item is returned without a build",
+				handler:     waitQueueItemHandler,
+			},
+		},
+		{
+			name:    "build-job",
+			args:    []argument{jobNameArg},
+			summary: "Trigger a build, once it leaves the queue optionally waiting for it or streaming its log",
+			write:   true,
+			setup: func(fs *flag.FlagSet) runFunc {
+				params := buildParams{}
+				fs.Var(params, "p", "Build parameter as KEY=VALUE (repeatable)")
+				wait := fs.Bool("wait", false, "Wait for the build to finish, then exit with its result")
+				interval := fs.Duration("interval", 10*time.Second, "With --wait, how often to poll the build")
+				follow := fs.Bool("follow", false, "Stream the build log until the build finishes, then exit with its result")
+				fs.BoolVar(follow, "f", false, "Shorthand for -follow")
+				return func(ctx context.Context, args []string, p printer) error {
+					if *wait && *follow {
+						return usageErrorf("--wait and --follow cannot be combined: --follow also waits for the build and exits with its result")
+					}
+					if *follow && output != "" && output != "text" {
+						return usageErrorf("--follow only supports text output")
+					}
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return buildJob(ctx, args[0], params, *wait, *interval, *follow, p)
+					})
+				}
+			},
+		},
+		{
+			name:    "abort-build",
+			args:    []argument{jobNameArg, buildNumberArg},
+			summary: "Abort a running build after confirmation, optionally escalating to term and kill",
+			write:   true,
+			setup: func(fs *flag.FlagSet) runFunc {
+				yes := fs.Bool("yes", false, "Abort without asking for confirmation")
+				fs.BoolVar(yes, "y", false, "Shorthand for -yes")
+				escalate := fs.Bool("escalate", false, "If the build is still running after the grace period, terminate it, and then kill it")
+				grace := fs.Duration("grace", 30*time.Second, "How long to wait for the build to stop before escalating")
+				return func(ctx context.Context, args []string, p printer) error {
+					return executeCommand(ctx, func(ctx context.Context) error {
+						return abortRunningBuild(ctx, args[0], args[1], *yes, *escalate, *grace, p)
+					})
+				}
+			},
+		},
+		{
+			name:        "mcp-server",
+			summary:     "Start MCP server (Model Context Protocol)",
+			description: "The server serves the MCP tools of the commands over stdio, using the same settings as the CLI.",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return runMCPServer(ctx)
+			},
+		},
+		{
+			name:    "help",
+			args:    []argument{{name: "command", description: "Command to show the arguments and flags of", optional: true}},
+			summary: "Show the usage, or the arguments and flags of a command",
+			run: func(ctx context.Context, args []string, p printer) error {
+				return help(os.Stdout, args)
+			},
+		},
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/bndr/gojenkins"
+	"github.com/kitproj/jenkins-cli/internal/config"
+	"golang.org/x/term"
+)
+
+// configureOptions are the settings saved by configure besides the URL and username
+type configureOptions struct {
+	allowWrite bool
+	tls        config.TLS
+	http       config.HTTP
+	// tokenBackend is where to store or read the token, or empty for the keyring, falling back to the file
+	tokenBackend string
+	// tokenCommand is the shell command printing the token, for the command backend
+	tokenCommand string
+	// noVerify saves the token without checking that Jenkins accepts it
+	noVerify bool
+	// tokenFile is a file to read the token from, instead of stdin
+	tokenFile string
+	// tokenEnv is an env var to read the token from, instead of stdin
+	tokenEnv string
+	// given are the names of the flags given, as reconfiguring a context keeps the settings of the others
+	given map[string]bool
+}
+
+// stdin is where configure reads a token that is not given otherwise
+var stdin io.Reader = os.Stdin
+
+// stdinIsTerminal reports whether stdin is a terminal, so that the token can be asked for with hidden input.
+// Otherwise it is read from stdin as piped.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(syscall.Stdin))
+}
+
+// configure gets the token, checks that Jenkins accepts it and stores it with the token backend, and saves the
+// username, whether write access is enabled, the TLS and HTTP settings and the token backend in the context in use,
+// which becomes the current context
+func configure(ctx context.Context, jenkinsURL, username string, opts configureOptions) error {
+	if jenkinsURL == "" {
+		return usageErrorf("Jenkins URL is required")
+	}
+
+	if opts.tokenCommand != "" {
+		if opts.tokenBackend != "" && opts.tokenBackend != config.TokenBackendCommand {
+			return usageErrorf("--token-command cannot be combined with --token-backend %s", opts.tokenBackend)
+		}
+		opts.tokenBackend = config.TokenBackendCommand
+	}
+	if opts.tokenBackend != "" && !slices.Contains(config.TokenBackends, opts.tokenBackend) {
+		return usageErrorf("invalid token backend: %s (must be one of keyring, file, netrc or command)", opts.tokenBackend)
+	}
+	if opts.tokenBackend == config.TokenBackendCommand && opts.tokenCommand == "" {
+		return usageErrorf("the command token backend requires --token-command")
+	}
+	if opts.tokenFile != "" && opts.tokenEnv != "" {
+		return usageErrorf("--token-file and --token-env cannot be combined")
+	}
+	if (opts.tokenFile != "" || opts.tokenEnv != "") && (opts.tokenBackend == config.TokenBackendNetrc || opts.tokenBackend == config.TokenBackendCommand) {
+		return usageErrorf("--token-file and --token-env cannot be used with the %s token backend, which reads the token itself", opts.tokenBackend)
+	}
+
+	if err := opts.keepSettings(); err != nil {
+		return err
+	}
+
+	// Check the TLS and HTTP settings before asking for the token, and store absolute paths so they work from any directory
+	for _, path := range []*string{&opts.tls.CAFile, &opts.tls.ClientCert, &opts.tls.ClientKey} {
+		if *path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		*path = abs
+	}
+	if _, err := newHTTPClient(opts.tls, opts.http); err != nil {
+		return err
+	}
+
+	if username == "" {
+		username = "admin"
+	}
+
+	var token string
+	var err error
+	switch opts.tokenBackend {
+	case config.TokenBackendNetrc, config.TokenBackendCommand:
+		// These backends only read tokens, so check that they have one
+		token, err = config.LoadTokenFrom(opts.tokenBackend, opts.tokenCommand, jenkinsURL)
+		if err != nil {
+			return err
+		}
+	default:
+		token, err = readToken(jenkinsURL, username, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.noVerify {
+		who, err := verifyToken(ctx, jenkinsURL, username, token, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Authenticated as %s", who.Name)
+		// whoAmI reports the groups of the user, as authorities, but not their permissions
+		if len(who.Authorities) > 0 {
+			fmt.Fprintf(os.Stderr, " in groups: %s", strings.Join(who.Authorities, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	backend, err := storeToken(opts.tokenBackend, jenkinsURL, token)
+	if err != nil {
+		return err
+	}
+
+	name, err := config.SaveContext(config.Context{
+		URL:          jenkinsURL,
+		Username:     username,
+		AllowWrite:   opts.allowWrite,
+		TokenBackend: backend,
+		TokenCommand: opts.tokenCommand,
+		TLS:          opts.tls,
+		HTTP:         opts.http,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Configuration saved successfully as context %q for URL: %s (username: %s, override with JENKINS_USER env var)\n", name, jenkinsURL, username)
+	return nil
+}
+
+// keepSettings sets the write access, TLS and HTTP settings whose flags were not given to those of the context
+// being reconfigured, if it exists
+func (opts *configureOptions) keepSettings() error {
+	saved, err := config.LoadContext()
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, config.ErrUnknownContext) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	allowWrite, tls, http := saved.AllowWrite, saved.TLS, saved.HTTP
+
+	if !opts.given["allow-write"] {
+		opts.allowWrite = allowWrite
+	}
+	if !opts.given["ca-file"] {
+		opts.tls.CAFile = tls.CAFile
+	}
+	if !opts.given["client-cert"] {
+		opts.tls.ClientCert = tls.ClientCert
+	}
+	if !opts.given["client-key"] {
+		opts.tls.ClientKey = tls.ClientKey
+	}
+	if !opts.given["insecure-skip-verify"] {
+		opts.tls.InsecureSkipVerify = tls.InsecureSkipVerify
+	}
+	if !opts.given["timeout"] {
+		opts.http.Timeout = http.Timeout
+	}
+	if !opts.given["proxy"] {
+		opts.http.Proxy = http.Proxy
+	}
+	if !opts.given["retries"] {
+		opts.http.Retries = http.Retries
+	}
+	return nil
+}
+
+// verifyToken checks that Jenkins accepts the token, returning who it authenticates as
+func verifyToken(ctx context.Context, jenkinsURL, username, token string, opts configureOptions) (*whoAmI, error) {
+	httpClient, err := newHTTPClient(opts.tls, opts.http)
+	if err != nil {
+		return nil, err
+	}
+	client := gojenkins.CreateJenkins(httpClient, jenkinsURL, username, token)
+	who, err := fetchWhoAmI(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the token: %w (check the URL, username and token, or pass --no-verify to save them anyway)", err)
+	}
+	if !who.Authenticated || who.Anonymous {
+		err := fmt.Errorf("Jenkins did not accept the token for %s (check the username and token, or pass --no-verify to save them anyway)", username)
+		return nil, &kindError{kind: kindUnauthorized, err: err}
+	}
+	return who, nil
+}
+
+// readToken reads the token from the token file or env var if given, else from stdin: asking for it with hidden
+// input on a terminal, or reading it as piped, e.g. in a Dockerfile
+func readToken(jenkinsURL, username string, opts configureOptions) (string, error) {
+	var token string
+	switch {
+	case opts.tokenFile != "":
+		data, err := os.ReadFile(opts.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = string(data)
+	case opts.tokenEnv != "":
+		token = os.Getenv(opts.tokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("env var %s is not set", opts.tokenEnv)
+		}
+	case !stdinIsTerminal():
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token from stdin: %w", err)
+		}
+		token = string(data)
+	default:
+		// Display the URL to the user
+		fmt.Fprintf(os.Stderr, "To create an API token in Jenkins:\n")
+		fmt.Fprintf(os.Stderr, "1. Go to: %s/user/%s/configure\n", jenkinsURL, username)
+		fmt.Fprintf(os.Stderr, "2. Click 'Add new Token' under API Token section\n")
+		fmt.Fprintf(os.Stderr, "3. Copy the generated token\n")
+		fmt.Fprintf(os.Stderr, "\nEnter Jenkins API token: ")
+
+		// Read password with hidden input
+		tokenBytes, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr) // Print newline after hidden input
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		token = string(tokenBytes)
+	}
+
+	// Files and pipes usually end with a newline, which is not part of the token
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token cannot be empty")
+	}
+	return token, nil
+}
+
+// storeToken stores the token with the token backend, and reports which it used. Without a backend, the keyring is
+// tried first, and the encrypted file used if it is not available.
+func storeToken(backend, jenkinsURL, token string) (string, error) {
+	switch backend {
+	case config.TokenBackendNetrc:
+		fmt.Fprintf(os.Stderr, "The token will be read from the netrc file\n")
+		return backend, nil
+	case config.TokenBackendCommand:
+		fmt.Fprintf(os.Stderr, "The token will be read from the token command\n")
+		return backend, nil
+	case "":
+		err := config.StoreToken(config.TokenBackendKeyring, jenkinsURL, token)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "Token stored in the system keyring\n")
+			return config.TokenBackendKeyring, nil
+		}
+		fmt.Fprintf(os.Stderr, "The system keyring is not available (%v), using the encrypted token file instead\n", err)
+		backend = config.TokenBackendFile
+	}
+
+	if err := config.StoreToken(backend, jenkinsURL, token); err != nil {
+		return "", fmt.Errorf("failed to store token in the %s backend: %w", backend, err)
+	}
+	if backend == config.TokenBackendFile && os.Getenv("JENKINS_TOKEN_PASSPHRASE") == "" {
+		fmt.Fprintf(os.Stderr, "Token stored in the encrypted token file, with a key file next to it (set JENKINS_TOKEN_PASSPHRASE to use a passphrase instead)\n")
+	} else if backend == config.TokenBackendFile {
+		fmt.Fprintf(os.Stderr, "Token stored in the encrypted token file, with the key derived from JENKINS_TOKEN_PASSPHRASE\n")
+	} else {
+		fmt.Fprintf(os.Stderr, "Token stored in the system keyring\n")
+	}
+	return backend, nil
+}
+
+// logout deletes the named context, or the context in use, and its stored token, unless another context uses
+// the same token
+func logout(name string) error {
+	if name == "" {
+		var err error
+		name, err = config.ContextName()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, contexts, err := config.ListContexts()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(contexts, func(c config.Context) bool { return c.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s (see 'jenkins list-contexts')", config.ErrUnknownContext, name)
+	}
+	target := contexts[i]
+
+	// Tokens are stored by URL, so keep one still used by another context
+	shared := slices.ContainsFunc(contexts, func(c config.Context) bool {
+		return c.Name != name && c.URL == target.URL && c.TokenBackend == target.TokenBackend
+	})
+	if !shared {
+		if err := config.DeleteTokenFrom(target.TokenBackend, target.URL); err != nil {
+			return fmt.Errorf("failed to delete token: %w", err)
+		}
+	}
+	if err := config.DeleteContext(name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Logged out of %s and deleted context %q\n", target.URL, name)
+	if shared {
+		fmt.Fprintf(os.Stderr, "The token was kept as another context uses it\n")
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+	"github.com/zalando/go-keyring"
+)
+
+// TestStoreToken_FallsBackToFile tests that the token goes to the encrypted file when no keyring is available
+func TestStoreToken_FallsBackToFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	keyring.MockInitWithError(errors.New("no secret service"))
+	defer keyring.MockInit()
+
+	backend, err := storeToken("", "https://jenkins.example.com", "secret")
+	if err != nil {
+		t.Fatalf("storeToken returned error: %v", err)
+	}
+	if backend != config.TokenBackendFile {
+		t.Errorf("Expected the file backend, got %q", backend)
+	}
+	if token, err := config.LoadTokenFrom(backend, "", "https://jenkins.example.com"); err != nil || token != "secret" {
+		t.Errorf("Expected the stored token, got %q (%v)", token, err)
+	}
+}
+
+// TestConfigure_ReadOnlyBackends tests configuring a context whose token is read from a command or the netrc file
+func TestConfigure_ReadOnlyBackends(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("JENKINS_URL", "")
+	t.Setenv("JENKINS_TOKEN", "")
+	t.Setenv("JENKINS_USER", "")
+	defer config.SelectContext("")
+	if err := os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine ci.example.com login bob password netrc-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	config.SelectContext("vault")
+	if err := configure(ctx, "https://jenkins.example.com", "alice", configureOptions{tokenCommand: "echo command-token", noVerify: true}); err != nil {
+		t.Fatalf("configure returned error: %v", err)
+	}
+	config.SelectContext("ci")
+	if err := configure(ctx, "https://ci.example.com", "bob", configureOptions{tokenBackend: config.TokenBackendNetrc, noVerify: true}); err != nil {
+		t.Fatalf("configure returned error: %v", err)
+	}
+	if err := configure(ctx, "https://ci.example.com", "bob", configureOptions{tokenBackend: config.TokenBackendCommand, noVerify: true}); err == nil {
+		t.Error("Expected an error for the command backend without a command")
+	}
+	if err := configure(ctx, "https://missing.example.com", "bob", configureOptions{tokenBackend: config.TokenBackendNetrc, noVerify: true}); err == nil {
+		t.Error("Expected an error for a host without a netrc entry")
+	}
+
+	config.SelectContext("vault")
+	creds, err := config.Resolve(config.Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Token != "command-token" || creds.TokenSource != config.SourceCommand {
+		t.Errorf("Unexpected token %q from %q", creds.Token, creds.TokenSource)
+	}
+
+	config.SelectContext("")
+	creds, err = config.Resolve(config.Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Context != "ci" || creds.Token != "netrc-token" || creds.TokenSource != config.SourceNetrc {
+		t.Errorf("Expected the netrc token of the current ci context, got %+v", creds)
+	}
+}
+
+// TestRun_WriteCommandTokenCommand tests that a write command runs the token command once, and reports it failing
+// rather than a lack of write access
+func TestRun_WriteCommandTokenCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("JENKINS_URL", "")
+	t.Setenv("JENKINS_TOKEN", "")
+	t.Setenv("JENKINS_USER", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"jobs":[]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	runs := filepath.Join(dir, "runs")
+	command := "echo run >> " + runs + " && echo command-token"
+	if err := configure(ctx, server.URL, "alice", configureOptions{tokenCommand: command, allowWrite: true, noVerify: true}); err != nil {
+		t.Fatalf("configure returned error: %v", err)
+	}
+	// configure runs the command too, to check it
+	os.Remove(runs)
+	if err := run(ctx, []string{"abort-build", "app", "1"}); classifyError(err) != kindNotFound {
+		t.Errorf("run(abort-build) = %v, want not found", err)
+	}
+	if data, err := os.ReadFile(runs); err != nil || string(data) != "run\n" {
+		t.Errorf("Expected the token command to run once, got %q (%v)", data, err)
+	}
+
+	saved, err := config.LoadContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved.TokenCommand = "exit 3"
+	if _, err := config.SaveContext(saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, []string{"abort-build", "app", "1"}); err == nil || !strings.Contains(err.Error(), "token command failed") {
+		t.Errorf("run(abort-build) = %v, want the token command's error", err)
+	}
+}
+
+// TestConfigure_Verify tests that configure only saves a token Jenkins accepts
+func TestConfigure_Verify(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_URL", "")
+	keyring.MockInit()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimSuffix(r.URL.Path, "/") != "/whoAmI/api/json" {
+			http.NotFound(w, r)
+			return
+		}
+		if _, password, _ := r.BasicAuth(); password != "good-token" {
+			w.Write([]byte(`{"name":"anonymous","authenticated":true,"anonymous":true,"authorities":["anonymous"]}`))
+			return
+		}
+		w.Write([]byte(`{"name":"alice","authenticated":true,"anonymous":false,"authorities":["authenticated"]}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	opts := configureOptions{tokenCommand: "echo bad-token"}
+	if err := configure(ctx, srv.URL, "alice", opts); err == nil || !strings.Contains(err.Error(), "did not accept the token") {
+		t.Errorf("Expected the token to be rejected, got: %v", err)
+	}
+	if creds, err := config.Resolve(config.Overrides{}); err != nil || creds.URL != "" {
+		t.Errorf("Expected nothing to be saved for a rejected token, got %+v (%v)", creds, err)
+	}
+
+	opts.tokenCommand = "echo good-token"
+	if err := configure(ctx, srv.URL, "alice", opts); err != nil {
+		t.Fatalf("configure returned error: %v", err)
+	}
+	if creds, err := config.Resolve(config.Overrides{}); err != nil || creds.URL != srv.URL {
+		t.Errorf("Expected the config to be saved, got %+v (%v)", creds, err)
+	}
+
+	who, err := verifyToken(ctx, srv.URL, "alice", "good-token", configureOptions{})
+	if err != nil || who.Name != "alice" {
+		t.Errorf("Expected alice, got %+v (%v)", who, err)
+	}
+}
+
+// TestLogout tests deleting a context and its token, keeping a token another context shares
+func TestLogout(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	keyring.MockInit()
+	defer config.SelectContext("")
+
+	for _, name := range []string{"staging", "prod-admin", "prod"} {
+		url := "https://" + strings.TrimSuffix(name, "-admin") + ".example.com"
+		config.SelectContext(name)
+		if _, err := config.SaveContext(config.Context{URL: url, Username: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.SaveToken(url, name+"-token"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.SelectContext("")
+
+	// prod is current, and shares its token with prod-admin
+	if err := logout(""); err != nil {
+		t.Fatalf("logout returned error: %v", err)
+	}
+	if _, err := config.LoadToken("https://prod.example.com"); err != nil {
+		t.Errorf("Expected the shared token to be kept, got: %v", err)
+	}
+	if err := logout("staging"); err != nil {
+		t.Fatalf("logout returned error: %v", err)
+	}
+	if _, err := config.LoadToken("https://staging.example.com"); err == nil {
+		t.Error("Expected the staging token to be deleted")
+	}
+
+	_, contexts, err := config.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contexts) != 1 || contexts[0].Name != "prod-admin" {
+		t.Errorf("Expected only prod-admin to be left, got %+v", contexts)
+	}
+	if err := logout("staging"); err == nil || !strings.Contains(err.Error(), "unknown context") {
+		t.Errorf("Expected unknown context error, got: %v", err)
+	}
+}
+
+// TestConfigure_NonInteractive tests reading the token from a pipe, a file or an env var
+func TestConfigure_NonInteractive(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	keyring.MockInit()
+	oldStdin, oldIsTerminal := stdin, stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdin, stdinIsTerminal = oldStdin, oldIsTerminal }()
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CI_JENKINS_TOKEN", "env-token")
+	t.Setenv("CI_EMPTY", "")
+
+	tests := []struct {
+		name      string
+		stdin     string
+		opts      configureOptions
+		wantToken string
+		wantErr   string
+	}{
+		{"piped", "piped-token\n", configureOptions{}, "piped-token", ""},
+		{"token file", "", configureOptions{tokenFile: tokenFile}, "file-token", ""},
+		{"token env", "", configureOptions{tokenEnv: "CI_JENKINS_TOKEN"}, "env-token", ""},
+		{"empty pipe", "\n", configureOptions{}, "", "token cannot be empty"},
+		{"missing token file", "", configureOptions{tokenFile: filepath.Join(dir, "missing")}, "", "failed to read token file"},
+		{"unset env var", "", configureOptions{tokenEnv: "CI_EMPTY"}, "", "CI_EMPTY is not set"},
+		{"file and env", "", configureOptions{tokenFile: tokenFile, tokenEnv: "CI_JENKINS_TOKEN"}, "", "cannot be combined"},
+		{"file with read-only backend", "", configureOptions{tokenFile: tokenFile, tokenCommand: "echo x"}, "", "reads the token itself"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := "https://" + strings.ReplaceAll(tt.name, " ", "-") + ".example.com"
+			stdin = strings.NewReader(tt.stdin)
+			tt.opts.noVerify = true
+			err := configure(context.Background(), url, "ci", tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected %q error, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configure returned error: %v", err)
+			}
+			if token, err := config.LoadToken(url); err != nil || token != tt.wantToken {
+				t.Errorf("Expected token %q, got %q (%v)", tt.wantToken, token, err)
+			}
+		})
+	}
+}
+
+// TestConfigure_KeepsSettings tests that reconfiguring a context only changes the settings whose flags are given
+func TestConfigure_KeepsSettings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	keyring.MockInit()
+	oldStdin, oldIsTerminal := stdin, stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdin, stdinIsTerminal = oldStdin, oldIsTerminal }()
+
+	ctx := context.Background()
+	retries := 5
+	configureWith := func(opts configureOptions) {
+		t.Helper()
+		stdin = strings.NewReader("token\n")
+		opts.noVerify = true
+		if err := configure(ctx, "https://jenkins.example.com", "alice", opts); err != nil {
+			t.Fatalf("configure returned error: %v", err)
+		}
+	}
+	configureWith(configureOptions{
+		allowWrite: true,
+		http:       config.HTTP{Proxy: "http://proxy.example.com:3128", Retries: &retries},
+		given:      map[string]bool{"allow-write": true, "proxy": true, "retries": true},
+	})
+
+	configureWith(configureOptions{http: config.HTTP{Timeout: "1m"}, given: map[string]bool{"timeout": true}})
+	saved, _ := config.LoadContext()
+	allowWrite, settings := saved.AllowWrite, saved.HTTP
+	if !allowWrite || settings.Proxy != "http://proxy.example.com:3128" || settings.Retries == nil || *settings.Retries != 5 || settings.Timeout != "1m" {
+		t.Errorf("Expected the settings to be kept, got allow write %v and %+v", allowWrite, settings)
+	}
+
+	configureWith(configureOptions{given: map[string]bool{"allow-write": true, "proxy": true}})
+	saved, _ = config.LoadContext()
+	allowWrite, settings = saved.AllowWrite, saved.HTTP
+	if allowWrite || settings.Proxy != "" || settings.Timeout != "1m" {
+		t.Errorf("Expected write access and the proxy to be turned off, got allow write %v and %+v", allowWrite, settings)
+	}
+}
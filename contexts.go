@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// contextInfo is a named Jenkins server in the config file
+type contextInfo struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Username   string `json:"username,omitempty"`
+	AllowWrite bool   `json:"allowWrite"`
+	// Current is true for the context used unless --context or JENKINS_CONTEXT selects another
+	Current bool `json:"current"`
+}
+
+// contextList is the result of listing the configured contexts
+type contextList struct {
+	Contexts []contextInfo `json:"contexts"`
+}
+
+func (l *contextList) writeText(w io.Writer) {
+	if len(l.Contexts) == 0 {
+		fmt.Fprintln(w, "No contexts configured, run 'jenkins configure <url> [username] --name NAME' to add one")
+		return
+	}
+
+	for _, c := range l.Contexts {
+		marker := " "
+		if c.Current {
+			marker = "*"
+		}
+		access := "read-only"
+		if c.AllowWrite {
+			access = "read-write"
+		}
+		fmt.Fprintf(w, "%s %-20s %-50s %-20s %s\n", marker, c.Name, c.URL, c.Username, access)
+	}
+}
+
+// listContexts lists the configured contexts, marking the current one
+func listContexts(p printer) error {
+	list, err := fetchContexts()
+	if err != nil {
+		return err
+	}
+	return p(os.Stdout, list)
+}
+
+// fetchContexts reads the configured contexts
+func fetchContexts() (*contextList, error) {
+	current, contexts, err := config.ListContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	list := &contextList{Contexts: []contextInfo{}}
+	for _, c := range contexts {
+		list.Contexts = append(list.Contexts, contextInfo{
+			Name:       c.Name,
+			URL:        c.URL,
+			Username:   c.Username,
+			AllowWrite: c.AllowWrite,
+			Current:    c.Name == current,
+		})
+	}
+	return list, nil
+}
+
+// useContext makes the named context the current one
+func useContext(name string) error {
+	if err := config.UseContext(name); err != nil {
+		return fmt.Errorf("%w (see 'jenkins list-contexts')", err)
+	}
+	fmt.Fprintf(os.Stderr, "Switched to context %q\n", name)
+	return nil
+}
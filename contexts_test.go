@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// TestUseAndListContexts tests switching the current context and listing the contexts
+func TestUseAndListContexts(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer config.SelectContext("")
+
+	for name, url := range map[string]string{"prod": "https://prod.example.com", "staging": "https://staging.example.com"} {
+		config.SelectContext(name)
+		if _, err := config.SaveContext(config.Context{URL: url, Username: "alice"}); err != nil {
+			t.Fatalf("Failed to save context %s: %v", name, err)
+		}
+	}
+	config.SelectContext("")
+
+	if err := useContext("staging"); err != nil {
+		t.Fatalf("useContext returned error: %v", err)
+	}
+	if err := useContext("sandbox"); err == nil || !strings.Contains(err.Error(), "unknown context: sandbox") {
+		t.Errorf("Expected unknown context error, got: %v", err)
+	}
+
+	list, err := fetchContexts()
+	if err != nil {
+		t.Fatalf("fetchContexts returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	list.writeText(&buf)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "  prod ") || !strings.HasPrefix(lines[1], "* staging ") {
+		t.Errorf("Unexpected list:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// errorKind classifies an error, so that scripts can tell from the exit code and agents from the MCP error payload
+// whether e.g. a job does not exist or Jenkins cannot be reached
+type errorKind string
+
+const (
+	kindFailure      errorKind = "error"
+	kindUsage        errorKind = "invalid_usage"
+	kindNotFound     errorKind = "not_found"
+	kindUnauthorized errorKind = "unauthorized"
+	kindForbidden    errorKind = "forbidden"
+	kindNetwork      errorKind = "network"
+)
+
+// exitCode returns the process exit code for the kind of error
+func (k errorKind) exitCode() int {
+	switch k {
+	case kindUsage:
+		return exitUsage
+	case kindNotFound:
+		return exitNotFound
+	case kindUnauthorized:
+		return exitUnauthorized
+	case kindForbidden:
+		return exitForbidden
+	case kindNetwork:
+		return exitNetwork
+	default:
+		return exitError
+	}
+}
+
+// kindError is an error of a known kind
+type kindError struct {
+	kind errorKind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf returns an error for a command used with missing or invalid arguments or flags
+func usageErrorf(format string, args ...any) error {
+	return &kindError{kind: kindUsage, err: fmt.Errorf(format, args...)}
+}
+
+// usageError marks err, e.g. from validating a flag, as a usage error
+func usageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kindUsage, err: err}
+}
+
+// notFoundErrorf returns an error for something that does not exist in Jenkins
+func notFoundErrorf(format string, args ...any) error {
+	return &kindError{kind: kindNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// statusError reports an HTTP response from Jenkins with an error status
+type statusError struct {
+	Code   int
+	Status string
+}
+
+func (e *statusError) Error() string {
+	return e.Status
+}
+
+// newStatusError returns a statusError for a response
+func newStatusError(resp *http.Response) error {
+	return &statusError{Code: resp.StatusCode, Status: resp.Status}
+}
+
+// gojenkinsStatus matches the errors gojenkins returns for error statuses, which are the bare status code
+// or "Invalid status code returned: 404"
+var gojenkinsStatus = regexp.MustCompile(`^(?:Invalid status code returned: )?([1-5][0-9][0-9])$`)
+
+// classifyError returns the kind of an error
+func classifyError(err error) errorKind {
+	if kindErr := (*kindError)(nil); errors.As(err, &kindErr) {
+		return kindErr.kind
+	}
+	// An unknown --context or JENKINS_CONTEXT is a mistake in how the command was run
+	if errors.Is(err, config.ErrUnknownContext) {
+		return kindUsage
+	}
+	if status := errorStatus(err); status != 0 {
+		return statusKind(status)
+	}
+	// Cancellation, e.g. by Ctrl-C, is not a failure of Jenkins
+	if errors.Is(err, context.Canceled) {
+		return kindFailure
+	}
+	// Failing to connect, resolve the host, complete the TLS handshake or get a response in time.
+	// The HTTP client wraps these in a *url.Error, which is also a net.Error.
+	if netErr := net.Error(nil); errors.As(err, &netErr) {
+		return kindNetwork
+	}
+	return kindFailure
+}
+
+// errorStatus returns the HTTP status code an error reports, or 0 if it does not report one
+func errorStatus(err error) int {
+	if statusErr := (*statusError)(nil); errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if m := gojenkinsStatus.FindStringSubmatch(e.Error()); m != nil {
+			code, _ := strconv.Atoi(m[1])
+			return code
+		}
+	}
+	return 0
+}
+
+// statusKind returns the kind of error for an HTTP status. Jenkins being down or restarting usually shows as a
+// 502, 503 or 504 from the proxy in front of it, so they count as network errors.
+func statusKind(code int) errorKind {
+	switch code {
+	case http.StatusNotFound:
+		return kindNotFound
+	case http.StatusUnauthorized:
+		return kindUnauthorized
+	case http.StatusForbidden:
+		return kindForbidden
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return kindNetwork
+	default:
+		return kindFailure
+	}
+}
+
+// errorPayload is the structured content of an MCP tool error
+type errorPayload struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Kind    errorKind `json:"kind"`
+	Message string    `json:"message"`
+	// Status is the HTTP status code Jenkins responded with, if any
+	Status int `json:"status,omitempty"`
+}
+
+// newErrorPayload describes an error for an MCP client
+func newErrorPayload(err error) errorPayload {
+	return errorPayload{Error: errorDetail{Kind: classifyError(err), Message: err.Error(), Status: errorStatus(err)}}
+}
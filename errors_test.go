@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// TestClassifyError tests that errors are classified by kind, whichever way they report it
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorKind
+	}{
+		{"usage", usageErrorf("usage: jenkins get-job <job-name>"), kindUsage},
+		{"flag validation", usageError(errors.New("invalid regular expression")), kindUsage},
+		{"unknown context", fmt.Errorf("%w: prod", config.ErrUnknownContext), kindUsage},
+		{"not found", notFoundErrorf("stage not found: Deploy"), kindNotFound},
+		{"status 404", fmt.Errorf("failed to get build log: %w", &statusError{Code: 404, Status: "404 Not Found"}), kindNotFound},
+		{"status 401", &statusError{Code: 401, Status: "401 Unauthorized"}, kindUnauthorized},
+		{"status 403", &statusError{Code: 403, Status: "403 Forbidden"}, kindForbidden},
+		{"status 503", &statusError{Code: 503, Status: "503 Service Unavailable"}, kindNetwork},
+		{"status 500", &statusError{Code: 500, Status: "500 Internal Server Error"}, kindFailure},
+		{"gojenkins status", fmt.Errorf("failed to get job: %w", errors.New("404")), kindNotFound},
+		{"gojenkins invalid status", errors.New("Invalid status code returned: 403"), kindForbidden},
+		{"canceled", fmt.Errorf("failed to get job: %w", context.Canceled), kindFailure},
+		{"other", errors.New("token cannot be empty"), kindFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConnect_Errors tests that failing to connect reports a rejected token and an unreachable server distinctly
+func TestConnect_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := connect(context.Background(), &config.Credentials{URL: server.URL, Username: "admin", Token: "wrong"})
+	if kind := classifyError(err); kind != kindUnauthorized {
+		t.Errorf("Rejected token: got %s (%v), want %s", kind, err, kindUnauthorized)
+	}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	_, err = connect(context.Background(), &config.Credentials{URL: down.URL, Username: "admin", Token: "test-token"})
+	if kind := classifyError(err); kind != kindNetwork {
+		t.Errorf("Server down: got %s (%v), want %s", kind, err, kindNetwork)
+	}
+	if code := kindNetwork.exitCode(); code != exitNetwork {
+		t.Errorf("exitCode() = %d, want %d", code, exitNetwork)
+	}
+	if code := classifyError(errors.New("unexpected")).exitCode(); code != exitError {
+		t.Errorf("exitCode() of an unclassified error = %d, want %d", code, exitError)
+	}
+}
+
+// TestRun_UsageErrors tests that mistakes in how a command is run are usage errors, and so print the usage
+func TestRun_UsageErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	for _, args := range [][]string{
+		{"get-job"},
+		{"no-such-command"},
+		{"list-builds", "app", "--no-such-flag"},
+		{"list-builds", "app", "--since", "soon"},
+	} {
+		err := run(context.Background(), args)
+		if kind := classifyError(err); kind != kindUsage {
+			t.Errorf("run(%q) = %v (%s), want a usage error", args, err, kind)
+		}
+	}
+}
+
+// TestToolError tests that MCP tool errors carry their kind as structured content
+func TestToolError(t *testing.T) {
+	client := newTestJenkins(t, nil)
+
+	result := callTestTool(t, client, "get_job", map[string]any{"job_name": "missing"})
+	if !result.IsError {
+		t.Fatalf("get_job = %+v, want a tool error", result)
+	}
+	payload, ok := result.StructuredContent.(errorPayload)
+	if !ok || payload.Error.Kind != kindNotFound || payload.Error.Status != http.StatusNotFound {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	result = callTestTool(t, client, "get_job", map[string]any{})
+	if payload, ok := result.StructuredContent.(errorPayload); !ok || payload.Error.Kind != kindUsage {
+		t.Errorf("Unexpected structured content for a missing argument: %+v", result.StructuredContent)
+	}
+}
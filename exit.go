@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bndr/gojenkins"
+)
+
+// Exit codes reported for a build by get-build --exit-status, get-build-log --follow and wait-build,
+// followed by those reported for errors by their kind. Any other error exits with exitError, so that it is not taken
+// for a failed build.
+const (
+	exitSuccess      = 0
+	exitFailure      = 1
+	exitUnstable     = 2
+	exitAborted      = 3
+	exitRunning      = 4
+	exitUsage        = 5
+	exitNotFound     = 6
+	exitUnauthorized = 7
+	exitForbidden    = 8
+	exitNetwork      = 9
+	exitError        = 10
+)
+
+// buildResultError reports a build that did not succeed, or is still running
+type buildResultError struct {
+	Number   int64
+	Result   string
+	Building bool
+}
+
+func (e *buildResultError) Error() string {
+	if e.Building {
+		return fmt.Sprintf("build #%d is still running", e.Number)
+	}
+	return fmt.Sprintf("build #%d finished with result %s", e.Number, e.Result)
+}
+
+// exitCode returns the process exit code for the build
+func (e *buildResultError) exitCode() int {
+	if e.Building {
+		return exitRunning
+	}
+	return resultExitCode(e.Result)
+}
+
+// resultExitCode maps a Jenkins build result to an exit code
+func resultExitCode(result string) int {
+	switch result {
+	case gojenkins.STATUS_SUCCESS:
+		return exitSuccess
+	case "UNSTABLE":
+		return exitUnstable
+	case gojenkins.STATUS_ABORTED, "NOT_BUILT":
+		return exitAborted
+	default:
+		return exitFailure
+	}
+}
+
+// checkBuildResult returns a buildResultError unless the build succeeded
+func checkBuildResult(build *buildDetail) error {
+	if build.Building || build.Result != gojenkins.STATUS_SUCCESS {
+		return &buildResultError{Number: build.Number, Result: build.Result, Building: build.Building}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestBuildResultExitCode tests the mapping of build results to exit codes
+func TestBuildResultExitCode(t *testing.T) {
+	tests := []struct {
+		build    buildDetail
+		expected int
+	}{
+		{buildDetail{Result: "SUCCESS"}, exitSuccess},
+		{buildDetail{Result: "FAILURE"}, exitFailure},
+		{buildDetail{Result: "UNSTABLE"}, exitUnstable},
+		{buildDetail{Result: "ABORTED"}, exitAborted},
+		{buildDetail{Result: "NOT_BUILT"}, exitAborted},
+		{buildDetail{Building: true}, exitRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.build.Status(), func(t *testing.T) {
+			err := checkBuildResult(&tt.build)
+			if tt.expected == exitSuccess {
+				if err != nil {
+					t.Errorf("checkBuildResult() = %v, want nil", err)
+				}
+				return
+			}
+			resultErr, ok := err.(*buildResultError)
+			if !ok {
+				t.Fatalf("checkBuildResult() = %v, want *buildResultError", err)
+			}
+			if code := resultErr.exitCode(); code != tt.expected {
+				t.Errorf("exitCode() = %d, want %d", code, tt.expected)
+			}
+		})
+	}
+}
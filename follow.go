@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// followInterval is how often a running build is polled for new log output
+var followInterval = 2 * time.Second
+
+// followBuildLog streams the console output of a build to w until the build finishes.
+// It returns a buildResultError if the build did not succeed.
+func followBuildLog(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string, w io.Writer) error {
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return err
+	}
+
+	// Jenkins returns the log from the start offset, the offset to continue from in X-Text-Size,
+	// and sets X-More-Data while the build may still write more output
+	var offset int64
+	for {
+		console, err := build.GetConsoleOutputFromIndex(ctx, offset)
+		if err != nil {
+			return fmt.Errorf("failed to get build log: %w", err)
+		}
+		if _, err := io.WriteString(w, console.Content); err != nil {
+			return err
+		}
+		offset = console.Offset
+		if !console.HasMoreText {
+			break
+		}
+		if err := sleep(ctx, followInterval); err != nil {
+			return err
+		}
+	}
+
+	// The log can be complete slightly before Jenkins records the result
+	detail, err := waitForBuild(ctx, build, followInterval)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Build #%d finished: %s\n", detail.Number, detail.Result)
+	return checkBuildResult(detail)
+}
+
+// waitForBuild polls a build every interval until it stops running
+func waitForBuild(ctx context.Context, build *gojenkins.Build, interval time.Duration) (*buildDetail, error) {
+	for {
+		status, err := build.Poll(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get build: %w", err)
+		}
+		// A build that is gone, or Jenkins failing, would otherwise be polled forever
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to get build: %w", &statusError{Code: status, Status: strconv.Itoa(status)})
+		}
+		if !build.Raw.Building {
+			return newBuildDetail(build), nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d, returning early with the context's error if it is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
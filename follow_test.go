@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestFollowBuildLog tests that a running build's log is streamed in chunks until the build finishes
+func TestFollowBuildLog(t *testing.T) {
+	oldInterval := followInterval
+	followInterval = time.Millisecond
+	defer func() { followInterval = oldInterval }()
+
+	chunks := []string{"Started\n", "Running tests\n", "Finished: FAILURE\n"}
+	var starts []string
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"result":"FAILURE","building":false}`))
+		},
+		"/job/app/7/logText/progressiveText/": func(w http.ResponseWriter, r *http.Request) {
+			start := r.URL.Query().Get("start")
+			starts = append(starts, start)
+			i := len(starts) - 1
+			offset := 0
+			for _, chunk := range chunks[:i+1] {
+				offset += len(chunk)
+			}
+			w.Header().Set("X-Text-Size", strconv.Itoa(offset))
+			if i < len(chunks)-1 {
+				w.Header().Set("X-More-Data", "true")
+			}
+			w.Write([]byte(chunks[i]))
+		},
+	})
+
+	var buf bytes.Buffer
+	err := followBuildLog(context.Background(), client, "app", "7", &buf)
+
+	var resultErr *buildResultError
+	if !errors.As(err, &resultErr) || resultErr.Result != "FAILURE" || resultErr.Number != 7 {
+		t.Errorf("Expected buildResultError for FAILURE, got: %v", err)
+	}
+	if want := "Started\nRunning tests\nFinished: FAILURE\n"; buf.String() != want {
+		t.Errorf("Expected log %q, got %q", want, buf.String())
+	}
+	if want := []string{"0", "8", "22"}; len(starts) != len(want) || starts[0] != want[0] || starts[1] != want[1] || starts[2] != want[2] {
+		t.Errorf("Expected start offsets %v, got %v", want, starts)
+	}
+}
+
+// TestFollowBuildLog_Cancelled tests that following stops when the context is cancelled
+func TestFollowBuildLog_Cancelled(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"building":true}`))
+		},
+		"/job/app/7/logText/progressiveText/": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Text-Size", "0")
+			w.Header().Set("X-More-Data", "true")
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := followBuildLog(ctx, client, "app", "7", &bytes.Buffer{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline exceeded, got: %v", err)
+	}
+}
+
+// TestWaitForBuild tests that a build is polled until it stops running
+func TestWaitForBuild(t *testing.T) {
+	polls := 0
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"number":7,"building":true}`))
+				return
+			}
+			w.Write([]byte(`{"number":7,"result":"UNSTABLE","building":false}`))
+		},
+	})
+
+	build, err := lookupBuild(context.Background(), client, "app", "7")
+	if err != nil {
+		t.Fatalf("lookupBuild returned error: %v", err)
+	}
+	detail, err := waitForBuild(context.Background(), build, time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForBuild returned error: %v", err)
+	}
+	if detail.Building || detail.Result != "UNSTABLE" || polls != 3 {
+		t.Errorf("Expected finished UNSTABLE build after 3 polls, got %+v after %d polls", detail, polls)
+	}
+}
+
+// TestWaitForBuild_Gone tests that waiting stops with a not found error when the build disappears, e.g. when it is
+// deleted, rather than polling forever
+func TestWaitForBuild_Gone(t *testing.T) {
+	polls := 0
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"number":7,"building":true}`))
+				return
+			}
+			http.NotFound(w, r)
+		},
+	})
+
+	build, err := lookupBuild(context.Background(), client, "app", "7")
+	if err != nil {
+		t.Fatalf("lookupBuild returned error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := waitForBuild(ctx, build, time.Millisecond); classifyError(err) != kindNotFound {
+		t.Errorf("waitForBuild() = %v, want a not found error", err)
+	}
+}
@@ -8,6 +8,7 @@ require (
 	github.com/mark3labs/mcp-go v0.43.0
 	github.com/zalando/go-keyring v0.2.6
 	golang.org/x/term v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/zalando/go-keyring => github.com/kitproj/go-keyring v0.2.10
@@ -25,5 +26,4 @@ require (
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	golang.org/x/net v0.0.0-20200114155413-6afb5195e5aa // indirect
 	golang.org/x/sys v0.37.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,9 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/zalando/go-keyring"
 )
@@ -14,9 +17,62 @@ const (
 	configFile  = "config.json"
 )
 
+// DefaultContext is the name of the context used when none has been named, and of the context an old
+// single-server config file is migrated to
+const DefaultContext = "default"
+
+// ErrUnknownContext is returned when the selected context is not in the config file
+var ErrUnknownContext = errors.New("unknown context")
+
+// selectedContext is the context chosen for this process, overriding the current context
+var selectedContext string
+
+// Context is a named Jenkins server
+type Context struct {
+	Name     string `json:"-"`
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	// AllowWrite enables commands that change Jenkins state, such as triggering builds
+	AllowWrite bool `json:"allow_write,omitempty"`
+	// TokenBackend is where the token is stored or read from: keyring (the default), file, netrc or command
+	TokenBackend string `json:"token_backend,omitempty"`
+	// TokenCommand is the shell command printing the token, for the command backend
+	TokenCommand string `json:"token_command,omitempty"`
+	TLS
+	HTTP
+}
+
+// TLS is how a context connects to a Jenkins server over HTTPS
+type TLS struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system's
+	CAFile string `json:"ca_file,omitempty"`
+	// ClientCert and ClientKey are the PEM certificate and key presented for mutual TLS
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// InsecureSkipVerify disables verification of the server's certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// HTTP is how a context's requests to Jenkins are made
+type HTTP struct {
+	// Timeout is how long to wait for Jenkins to connect and start responding, as a duration such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+	// Proxy is the URL of the proxy to use, instead of those in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars
+	Proxy string `json:"proxy,omitempty"`
+	// Retries is how many times a GET failing with a 502, 503 or 504 status or a connection reset is retried,
+	// unless nil for the default
+	Retries *int `json:"retries,omitempty"`
+}
+
 // config represents the jenkins-cli configuration
 type config struct {
-	URL      string `json:"url"`
+	// CurrentContext is the context used unless another is selected
+	CurrentContext string             `json:"current_context,omitempty"`
+	Contexts       map[string]Context `json:"contexts,omitempty"`
+
+	// URL and Username are the single server of a config file written before contexts existed, migrated to the
+	// default context when read
+	URL      string `json:"url,omitempty"`
 	Username string `json:"username,omitempty"`
 }
 
@@ -39,8 +95,123 @@ func getConfigPath() (string, error) {
 	return configPath, nil
 }
 
-// SaveConfig saves the URL and username to the config file
-func SaveConfig(url, username string) error {
+// SelectContext makes the other functions use the named context, rather than the current one, for this process.
+// An empty name selects the current context again.
+func SelectContext(name string) {
+	selectedContext = name
+}
+
+// ContextName returns the name of the context the other functions use: the selected context, else the current
+// context, else the default context
+func ContextName() (string, error) {
+	cfg, err := readConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	return cfg.contextName(), nil
+}
+
+// LoadContext loads the settings of the context in use, which must exist
+func LoadContext() (Context, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return Context{}, err
+	}
+	return cfg.context()
+}
+
+// SaveContext saves all the settings of the context in use at once, creating it if needed and replacing the settings
+// saved before, and makes it the current context. It returns the name of the context.
+func SaveContext(ctx Context) (string, error) {
+	cfg, err := readConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	name := cfg.contextName()
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]Context{}
+	}
+	if ctx.TokenBackend == TokenBackendKeyring {
+		// The default is left implicit
+		ctx.TokenBackend = ""
+	}
+	cfg.Contexts[name] = ctx
+	cfg.CurrentContext = name
+	return name, writeConfig(cfg)
+}
+
+// UseContext makes the named context the current context
+func UseContext(name string) error {
+	cfg, err := readConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownContext, name)
+	}
+	cfg.CurrentContext = name
+	return writeConfig(cfg)
+}
+
+// DeleteContext deletes the named context. If it was the current context, there is no current context afterwards.
+func DeleteContext(name string) error {
+	cfg, err := readConfig()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownContext, name)
+	}
+	delete(cfg.Contexts, name)
+	if cfg.CurrentContext == name {
+		cfg.CurrentContext = ""
+	}
+	return writeConfig(cfg)
+}
+
+// ListContexts returns the name of the current context and all the contexts, sorted by name
+func ListContexts() (string, []Context, error) {
+	cfg, err := readConfig()
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	contexts := make([]Context, 0, len(cfg.Contexts))
+	for _, name := range slices.Sorted(maps.Keys(cfg.Contexts)) {
+		ctx := cfg.Contexts[name]
+		ctx.Name = name
+		contexts = append(contexts, ctx)
+	}
+	return cfg.CurrentContext, contexts, nil
+}
+
+// contextName returns the name of the context in use
+func (c config) contextName() string {
+	switch {
+	case selectedContext != "":
+		return selectedContext
+	case c.CurrentContext != "":
+		return c.CurrentContext
+	default:
+		return DefaultContext
+	}
+}
+
+// context returns the context in use, which must exist
+func (c config) context() (Context, error) {
+	name := c.contextName()
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("%w: %s", ErrUnknownContext, name)
+	}
+	ctx.Name = name
+	return ctx, nil
+}
+
+// writeConfig writes the config file, creating its directory if needed
+func writeConfig(cfg config) error {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return err
@@ -52,40 +223,66 @@ func SaveConfig(url, username string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	cfg := config{URL: url, Username: username}
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
+	if err := writeFileAtomic(configPath, data); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// LoadConfig loads the URL and username from the config file
-func LoadConfig() (string, string, error) {
+// writeFileAtomic writes a private file by renaming a temporary file over it, so that it is never left half written
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readConfig reads the config file, migrating a file written before contexts existed to the default context
+func readConfig() (config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
-		return "", "", err
+		return config{}, err
 	}
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to read config file: %w", err)
+		return config{}, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	var cfg config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return "", "", fmt.Errorf("failed to parse config file: %w", err)
+		return config{}, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return cfg.URL, cfg.Username, nil
+	if cfg.Contexts == nil && cfg.URL != "" {
+		cfg.Contexts = map[string]Context{
+			DefaultContext: {URL: cfg.URL, Username: cfg.Username},
+		}
+		cfg.CurrentContext = DefaultContext
+		cfg.URL, cfg.Username = "", ""
+		// Rewriting the file is best effort, the migrated config is usable either way
+		_ = writeConfig(cfg)
+	}
+
+	return cfg, nil
 }
 
-// SaveToken saves the token to the keyring
+// SaveToken saves the token to the keyring, keyed by URL so that contexts for the same server share it
 func SaveToken(url, token string) error {
 	return keyring.Set(serviceName, url, token)
 }
@@ -94,3 +291,11 @@ func SaveToken(url, token string) error {
 func LoadToken(url string) (string, error) {
 	return keyring.Get(serviceName, url)
 }
+
+// DeleteToken deletes the token from the keyring. It is not an error if there is none.
+func DeleteToken(url string) error {
+	if err := keyring.Delete(serviceName, url); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -24,29 +27,33 @@ func TestSaveLoadConfig(t *testing.T) {
 	testURL := "https://jenkins.example.com/ci"
 	testUsername := "testuser"
 
-	// Test SaveConfig
-	err := SaveConfig(testURL, testUsername)
-	if err != nil {
+	name, err := SaveContext(Context{URL: testURL, Username: testUsername})
+	if err != nil || name != DefaultContext {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 
-	// Test LoadConfig
-	retrievedURL, retrievedUsername, err := LoadConfig()
-	if err != nil {
+	profile, err := loadProfile()
+	if err != nil || profile == nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
 
-	if retrievedURL != testURL {
-		t.Errorf("Expected URL %q, got %q", testURL, retrievedURL)
+	if profile.URL != testURL {
+		t.Errorf("Expected URL %q, got %q", testURL, profile.URL)
 	}
 
-	if retrievedUsername != testUsername {
-		t.Errorf("Expected username %q, got %q", testUsername, retrievedUsername)
+	if profile.Username != testUsername {
+		t.Errorf("Expected username %q, got %q", testUsername, profile.Username)
+	}
+
+	// The file is written through a temporary file, which is renamed over it
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "jenkins-cli"))
+	if err != nil || len(entries) != 1 || entries[0].Name() != configFile {
+		t.Errorf("Expected only the config file, got %v (%v)", entries, err)
 	}
 }
 
-// TestSaveConfigPreservesTrailingSlash tests that SaveConfig preserves trailing slashes
-func TestSaveConfigPreservesTrailingSlash(t *testing.T) {
+// TestSaveContextPreservesTrailingSlash tests that SaveContext preserves trailing slashes
+func TestSaveContextPreservesTrailingSlash(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir := t.TempDir()
 
@@ -65,20 +72,19 @@ func TestSaveConfigPreservesTrailingSlash(t *testing.T) {
 	expectedURL := "https://jenkins.example.com/"
 	testUsername := "testuser"
 
-	// Test SaveConfig with trailing slash
-	err := SaveConfig(testURLWithTrailingSlash, testUsername)
-	if err != nil {
+	// Save a URL with a trailing slash
+	if _, err := SaveContext(Context{URL: testURLWithTrailingSlash, Username: testUsername}); err != nil {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 
-	// Test LoadConfig - should return URL with trailing slash preserved
-	retrievedURL, _, err := LoadConfig()
-	if err != nil {
+	// The URL is loaded with its trailing slash preserved
+	profile, err := loadProfile()
+	if err != nil || profile == nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
 
-	if retrievedURL != expectedURL {
-		t.Errorf("Expected URL with trailing slash preserved %q, got %q", expectedURL, retrievedURL)
+	if profile.URL != expectedURL {
+		t.Errorf("Expected URL with trailing slash preserved %q, got %q", expectedURL, profile.URL)
 	}
 }
 
@@ -100,29 +106,27 @@ func TestSaveLoadConfigWithoutUsername(t *testing.T) {
 
 	testURL := "https://jenkins.example.com"
 
-	// Test SaveConfig without username
-	err := SaveConfig(testURL, "")
-	if err != nil {
+	// Save without a username
+	if _, err := SaveContext(Context{URL: testURL}); err != nil {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 
-	// Test LoadConfig
-	retrievedURL, retrievedUsername, err := LoadConfig()
-	if err != nil {
+	profile, err := loadProfile()
+	if err != nil || profile == nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
 
-	if retrievedURL != testURL {
-		t.Errorf("Expected URL %q, got %q", testURL, retrievedURL)
+	if profile.URL != testURL {
+		t.Errorf("Expected URL %q, got %q", testURL, profile.URL)
 	}
 
-	if retrievedUsername != "" {
-		t.Errorf("Expected empty username, got %q", retrievedUsername)
+	if profile.Username != "" {
+		t.Errorf("Expected empty username, got %q", profile.Username)
 	}
 }
 
-// TestLoadConfigNotFound tests error handling when config doesn't exist
-func TestLoadConfigNotFound(t *testing.T) {
+// TestLoadProfileNotFound tests that there is no context in use when the config doesn't exist
+func TestLoadProfileNotFound(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir := t.TempDir()
 
@@ -138,8 +142,259 @@ func TestLoadConfigNotFound(t *testing.T) {
 	}()
 
 	// Try to load from non-existent config
-	_, _, err := LoadConfig()
-	if err == nil {
-		t.Error("Expected error when loading non-existent config, got nil")
+	profile, err := loadProfile()
+	if err != nil || profile != nil {
+		t.Errorf("Expected no context without a config file, got %+v (%v)", profile, err)
+	}
+}
+
+// TestSaveLoadAllowWrite tests that write access is off by default
+func TestSaveLoadAllowWrite(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir := t.TempDir()
+
+	// Override the config directory
+	origConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer func() {
+		if origConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", origConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com", Username: "testuser"}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	saved, err := LoadContext()
+	if err != nil {
+		t.Fatalf("Failed to load write access: %v", err)
+	}
+	if saved.AllowWrite {
+		t.Error("Expected write access to be disabled by default")
+	}
+
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com", Username: "testuser", AllowWrite: true}); err != nil {
+		t.Fatalf("Failed to save write access: %v", err)
+	}
+	saved, err = LoadContext()
+	if err != nil {
+		t.Fatalf("Failed to load write access: %v", err)
+	}
+	if !saved.AllowWrite {
+		t.Error("Expected write access to be enabled")
+	}
+}
+
+// TestContexts tests saving, selecting, listing and switching named contexts
+func TestContexts(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir := t.TempDir()
+
+	// Override the config directory
+	origConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer func() {
+		if origConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", origConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+	defer SelectContext("")
+
+	SelectContext("staging")
+	if _, err := SaveContext(Context{URL: "https://staging.example.com", Username: "bob", AllowWrite: true}); err != nil {
+		t.Fatalf("Failed to save staging context: %v", err)
+	}
+	SelectContext("prod")
+	if _, err := SaveContext(Context{URL: "https://prod.example.com", Username: "alice"}); err != nil {
+		t.Fatalf("Failed to save prod context: %v", err)
+	}
+
+	// The last context saved is the current one
+	SelectContext("")
+	profile, err := loadProfile()
+	if err != nil || profile == nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if profile.URL != "https://prod.example.com" || profile.Username != "alice" || profile.AllowWrite {
+		t.Errorf("Expected the prod context without write access, got %+v", profile)
+	}
+
+	if err := UseContext("staging"); err != nil {
+		t.Fatalf("Failed to use staging context: %v", err)
+	}
+	if profile, err := loadProfile(); err != nil || profile == nil || profile.URL != "https://staging.example.com" || !profile.AllowWrite {
+		t.Errorf("Expected the staging context with write access, got %+v (%v)", profile, err)
+	}
+
+	current, contexts, err := ListContexts()
+	if err != nil {
+		t.Fatalf("Failed to list contexts: %v", err)
+	}
+	if current != "staging" || len(contexts) != 2 || contexts[0].Name != "prod" || contexts[1].Name != "staging" {
+		t.Errorf("Unexpected contexts: %q %+v", current, contexts)
+	}
+
+	if err := UseContext("sandbox"); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Expected unknown context error, got: %v", err)
+	}
+	SelectContext("sandbox")
+	if _, err := loadProfile(); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Expected unknown context error, got: %v", err)
+	}
+}
+
+// TestMigrateSingleServerConfig tests that a config file written before contexts existed becomes the default context
+func TestMigrateSingleServerConfig(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir := t.TempDir()
+
+	// Override the config directory
+	origConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer func() {
+		if origConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", origConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+
+	configPath := filepath.Join(tmpDir, "jenkins-cli", configFile)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := `{"url": "https://jenkins.example.com", "username": "testuser"}`
+	if err := os.WriteFile(configPath, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := loadProfile()
+	if err != nil || profile == nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if profile.URL != "https://jenkins.example.com" || profile.Username != "testuser" {
+		t.Errorf("Unexpected config: %q %q", profile.URL, profile.Username)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"current_context": "default"`) || !strings.Contains(string(data), `"contexts"`) {
+		t.Errorf("Expected the config file to be rewritten with contexts, got:\n%s", data)
+	}
+}
+
+// TestSaveLoadTLS tests that TLS settings are saved per context
+func TestSaveLoadTLS(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir := t.TempDir()
+
+	// Override the config directory
+	origConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer func() {
+		if origConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", origConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+	defer SelectContext("")
+
+	tls := TLS{CAFile: "/etc/ssl/corp-ca.pem", ClientCert: "/home/me/cert.pem", ClientKey: "/home/me/key.pem"}
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com", Username: "testuser", TLS: tls}); err != nil {
+		t.Fatalf("Failed to save TLS settings: %v", err)
+	}
+
+	loaded, err := LoadContext()
+	if err != nil {
+		t.Fatalf("Failed to load TLS settings: %v", err)
+	}
+	if loaded.TLS != tls {
+		t.Errorf("Expected %+v, got %+v", tls, loaded.TLS)
+	}
+
+	SelectContext("sandbox")
+	if _, err := SaveContext(Context{URL: "https://localhost:8443", Username: "admin"}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if loaded, err := LoadContext(); err != nil || loaded.TLS != (TLS{}) {
+		t.Errorf("Expected no TLS settings in another context, got %+v (%v)", loaded.TLS, err)
+	}
+}
+
+// TestSaveLoadHTTP tests that HTTP settings are saved, including explicitly disabled retries
+func TestSaveLoadHTTP(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir := t.TempDir()
+
+	// Override the config directory
+	origConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	defer func() {
+		if origConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", origConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com", Username: "testuser"}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	saved, err := LoadContext()
+	if err != nil {
+		t.Fatalf("Failed to load HTTP settings: %v", err)
+	}
+	if loaded := saved.HTTP; loaded.Timeout != "" || loaded.Proxy != "" || loaded.Retries != nil {
+		t.Errorf("Expected no HTTP settings by default, got %+v", loaded)
+	}
+
+	retries := 0
+	http := HTTP{Timeout: "45s", Proxy: "http://proxy.example.com:3128", Retries: &retries}
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com", Username: "testuser", HTTP: http}); err != nil {
+		t.Fatalf("Failed to save HTTP settings: %v", err)
+	}
+	saved, err = LoadContext()
+	loaded := saved.HTTP
+	if err != nil {
+		t.Fatalf("Failed to load HTTP settings: %v", err)
+	}
+	if loaded.Timeout != "45s" || loaded.Proxy != "http://proxy.example.com:3128" || loaded.Retries == nil || *loaded.Retries != 0 {
+		t.Errorf("Unexpected HTTP settings: %+v", loaded)
+	}
+}
+
+// TestDeleteContext tests deleting the current context
+func TestDeleteContext(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer SelectContext("")
+
+	for _, name := range []string{"staging", "prod"} {
+		SelectContext(name)
+		if _, err := SaveContext(Context{URL: "https://" + name + ".example.com", Username: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SelectContext("")
+
+	if err := DeleteContext("prod"); err != nil {
+		t.Fatalf("DeleteContext returned error: %v", err)
+	}
+	current, contexts, err := ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != "" || len(contexts) != 1 || contexts[0].Name != "staging" {
+		t.Errorf("Unexpected contexts after delete: %q %+v", current, contexts)
+	}
+	if err := DeleteContext("prod"); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Expected unknown context error, got: %v", err)
 	}
 }
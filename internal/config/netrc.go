@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcEntry is the login and password of a machine in a netrc file
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrcPath returns the path of the netrc file: $NETRC, or .netrc in the home directory
+func netrcPath() (string, error) {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// lookupNetrc finds the entry for host, which may include a port, in the netrc file, falling back to the
+// default entry. It returns false if the file does not exist or has no entry for the host.
+func lookupNetrc(host string) (netrcEntry, bool, error) {
+	path, err := netrcPath()
+	if err != nil {
+		return netrcEntry{}, false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return netrcEntry{}, false, nil
+	}
+	if err != nil {
+		return netrcEntry{}, false, fmt.Errorf("failed to read netrc file: %w", err)
+	}
+
+	entries, fallback := parseNetrc(string(data))
+	if entry, ok := entries[host]; ok {
+		return entry, true, nil
+	}
+	// A machine is usually named without the port
+	if hostname, _, ok := strings.Cut(host, ":"); ok {
+		if entry, ok := entries[hostname]; ok {
+			return entry, true, nil
+		}
+	}
+	if fallback != nil {
+		return *fallback, true, nil
+	}
+	return netrcEntry{}, false, nil
+}
+
+// parseNetrc parses the machine entries of a netrc file, the first for each machine winning, and its default entry
+func parseNetrc(data string) (map[string]netrcEntry, *netrcEntry) {
+	entries := map[string]netrcEntry{}
+	var fallback *netrcEntry
+
+	var machine string
+	var entry *netrcEntry
+	finish := func() {
+		if entry == nil {
+			return
+		}
+		if machine == "" {
+			if fallback == nil {
+				fallback = entry
+			}
+		} else if _, ok := entries[machine]; !ok {
+			entries[machine] = *entry
+		}
+		entry = nil
+	}
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			value := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				finish()
+				machine = value()
+				entry = &netrcEntry{}
+			case "default":
+				finish()
+				machine = ""
+				entry = &netrcEntry{}
+			case "login":
+				if entry != nil {
+					entry.login = value()
+				}
+			case "password":
+				if entry != nil {
+					entry.password = value()
+				}
+			case "account":
+				value()
+			case "macdef":
+				// A macro runs until the next blank line
+				finish()
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			default:
+				if strings.HasPrefix(fields[j], "#") {
+					j = len(fields)
+				}
+			}
+		}
+	}
+	finish()
+	return entries, fallback
+}
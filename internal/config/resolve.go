@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"strings"
+)
+
+// defaultUsername is the username used when none is configured
+const defaultUsername = "admin"
+
+// Source is where a resolved setting came from
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceConfig  Source = "config"
+	SourceKeyring Source = "keyring"
+	SourceNetrc   Source = "netrc"
+	SourceFile    Source = "file"
+	SourceCommand Source = "command"
+	SourceDefault Source = "default"
+)
+
+// Overrides are settings given on the command line, which take precedence over all others
+type Overrides struct {
+	URL      string
+	Username string
+}
+
+// Credentials are the settings needed to talk to Jenkins, with where each came from. A setting that could not be
+// resolved is empty, with an empty source.
+type Credentials struct {
+	// Context is the name of the context whose settings were used, or empty if none were
+	Context        string
+	URL            string
+	URLSource      Source
+	Username       string
+	UsernameSource Source
+	Token          string
+	TokenSource    Source
+	AllowWrite     bool
+	TLS            TLS
+	HTTP           HTTP
+}
+
+// Resolve resolves the settings needed to talk to Jenkins, each from the first of these that has it:
+//
+//  1. the overrides, from command line flags
+//  2. the JENKINS_URL, JENKINS_USER and JENKINS_TOKEN env vars
+//  3. the context in use in the config file, including its token backend if it is not the keyring
+//  4. the keyring, for the token
+//  5. the netrc file entry for the URL's host, for the username and token
+//
+// The username defaults to "admin". The settings of the context in use only apply if the URL is the context's,
+// so that the username, TLS settings and write access of one server are never used for another.
+func Resolve(overrides Overrides) (*Credentials, error) {
+	creds := &Credentials{}
+
+	profile, err := loadProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case overrides.URL != "":
+		creds.URL, creds.URLSource = overrides.URL, SourceFlag
+	case os.Getenv("JENKINS_URL") != "":
+		creds.URL, creds.URLSource = os.Getenv("JENKINS_URL"), SourceEnv
+	case profile != nil:
+		creds.URL, creds.URLSource = profile.URL, SourceConfig
+	}
+	if profile != nil && sameURL(profile.URL, creds.URL) {
+		creds.Context = profile.Name
+		creds.AllowWrite = profile.AllowWrite
+		creds.TLS = profile.TLS
+		creds.HTTP = profile.HTTP
+	} else {
+		profile = nil
+	}
+
+	var netrc netrcEntry
+	var hasNetrc bool
+	if creds.URL != "" {
+		if u, err := neturl.Parse(creds.URL); err == nil && u.Host != "" {
+			// An unreadable netrc file only loses a fallback, so it is not an error
+			netrc, hasNetrc, _ = lookupNetrc(u.Host)
+		}
+	}
+
+	switch {
+	case overrides.Username != "":
+		creds.Username, creds.UsernameSource = overrides.Username, SourceFlag
+	case os.Getenv("JENKINS_USER") != "":
+		creds.Username, creds.UsernameSource = os.Getenv("JENKINS_USER"), SourceEnv
+	case profile != nil && profile.Username != "":
+		creds.Username, creds.UsernameSource = profile.Username, SourceConfig
+	case hasNetrc && netrc.login != "":
+		creds.Username, creds.UsernameSource = netrc.login, SourceNetrc
+	default:
+		creds.Username, creds.UsernameSource = defaultUsername, SourceDefault
+	}
+
+	if token := os.Getenv("JENKINS_TOKEN"); token != "" {
+		creds.Token, creds.TokenSource = token, SourceEnv
+	} else if profile != nil && profile.TokenBackend != "" && profile.TokenBackend != TokenBackendKeyring {
+		// A token backend chosen for the context is expected to work, so its failure is an error
+		token, err := LoadTokenFrom(profile.TokenBackend, profile.TokenCommand, creds.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load token from the %s backend: %w", profile.TokenBackend, err)
+		}
+		creds.Token, creds.TokenSource = token, Source(profile.TokenBackend)
+	} else if creds.URL != "" {
+		if token, err := LoadToken(creds.URL); err == nil && token != "" {
+			creds.Token, creds.TokenSource = token, SourceKeyring
+		} else if hasNetrc && netrc.password != "" {
+			creds.Token, creds.TokenSource = netrc.password, SourceNetrc
+		}
+	}
+
+	return creds, nil
+}
+
+// loadProfile loads the context in use, or nil if there is no config file or it has no context to use.
+// A context selected by name that does not exist, including when there is no config file, is an error.
+func loadProfile() (*Context, error) {
+	cfg, err := readConfig()
+	if errors.Is(err, os.ErrNotExist) {
+		if selectedContext != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownContext, selectedContext)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := cfg.context()
+	if errors.Is(err, ErrUnknownContext) && selectedContext == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ctx, nil
+}
+
+// sameURL reports whether two Jenkins URLs are the same, ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// TestParseNetrc tests parsing machine and default entries, skipping macros and comments
+func TestParseNetrc(t *testing.T) {
+	data := `# CI credentials
+machine jenkins.example.com login alice password secret1
+machine other.example.com
+  login bob
+  account ignored
+  password secret2
+
+macdef init
+machine evil.example.com login mallory password nope
+
+default login anonymous password guest
+`
+	entries, fallback := parseNetrc(data)
+	if entries["jenkins.example.com"] != (netrcEntry{login: "alice", password: "secret1"}) {
+		t.Errorf("Unexpected jenkins.example.com entry: %+v", entries["jenkins.example.com"])
+	}
+	if entries["other.example.com"] != (netrcEntry{login: "bob", password: "secret2"}) {
+		t.Errorf("Unexpected other.example.com entry: %+v", entries["other.example.com"])
+	}
+	if _, ok := entries["evil.example.com"]; ok {
+		t.Error("Expected the macro body to be skipped")
+	}
+	if fallback == nil || fallback.login != "anonymous" {
+		t.Errorf("Unexpected default entry: %+v", fallback)
+	}
+}
+
+// TestResolve tests the precedence of flags, env vars, the config file, the keyring and the netrc file
+func TestResolve(t *testing.T) {
+	keyring.MockInit()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("JENKINS_URL", "")
+	t.Setenv("JENKINS_USER", "")
+	t.Setenv("JENKINS_TOKEN", "")
+	defer SelectContext("")
+
+	if err := os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine ci.example.com login carol password netrc-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveContext(Context{URL: "https://jenkins.example.com/", Username: "alice", AllowWrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveToken("https://jenkins.example.com/", "keyring-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything from the config file and keyring
+	creds, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Context != DefaultContext || creds.URL != "https://jenkins.example.com/" || creds.URLSource != SourceConfig ||
+		creds.Username != "alice" || creds.UsernameSource != SourceConfig ||
+		creds.Token != "keyring-token" || creds.TokenSource != SourceKeyring || !creds.AllowWrite {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	// Env vars override the config file
+	t.Setenv("JENKINS_USER", "dave")
+	t.Setenv("JENKINS_TOKEN", "env-token")
+	creds, err = Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Username != "dave" || creds.UsernameSource != SourceEnv || creds.Token != "env-token" || creds.TokenSource != SourceEnv {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	// Flags override env vars, and another server's settings come from the netrc file, not the config file
+	t.Setenv("JENKINS_USER", "")
+	t.Setenv("JENKINS_TOKEN", "")
+	creds, err = Resolve(Overrides{URL: "https://ci.example.com:8443"})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Context != "" || creds.URLSource != SourceFlag || creds.AllowWrite ||
+		creds.Username != "carol" || creds.UsernameSource != SourceNetrc ||
+		creds.Token != "netrc-token" || creds.TokenSource != SourceNetrc {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	// Without any settings, the username has a default and the rest is missing
+	creds, err = Resolve(Overrides{URL: "https://unknown.example.com", Username: "erin"})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Username != "erin" || creds.UsernameSource != SourceFlag || creds.Token != "" || creds.TokenSource != "" {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	SelectContext("missing")
+	if _, err := Resolve(Overrides{}); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+}
+
+// TestResolve_UnknownContextWithoutConfig tests that a context selected by name is an error without a config file,
+// rather than being ignored
+func TestResolve_UnknownContextWithoutConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_URL", "https://jenkins.example.com")
+	defer SelectContext("")
+
+	if _, err := Resolve(Overrides{}); err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	SelectContext("typo")
+	if _, err := Resolve(Overrides{}); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Resolve() = %v, want %v", err, ErrUnknownContext)
+	}
+}
@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// The token backends a context can store or read its token with
+const (
+	// TokenBackendKeyring stores tokens in the system keyring, which is the default
+	TokenBackendKeyring = "keyring"
+	// TokenBackendFile stores tokens in a file encrypted with AES-GCM
+	TokenBackendFile = "file"
+	// TokenBackendNetrc reads tokens from the password of the URL's host in the netrc file
+	TokenBackendNetrc = "netrc"
+	// TokenBackendCommand reads tokens from the output of the context's token command, e.g. a password manager
+	TokenBackendCommand = "command"
+)
+
+const (
+	tokenFile    = "tokens.enc"
+	tokenKeyFile = "tokens.key"
+	// pbkdf2Iterations is the cost of deriving the token file's key from a passphrase
+	pbkdf2Iterations = 600_000
+)
+
+// TokenBackends are the token backends, in the order configure tries those that can store a token
+var TokenBackends = []string{TokenBackendKeyring, TokenBackendFile, TokenBackendNetrc, TokenBackendCommand}
+
+// StoreToken stores a token for a URL in a token backend. Only the keyring and file backends can store tokens.
+func StoreToken(backend, url, token string) error {
+	switch backend {
+	case "", TokenBackendKeyring:
+		return SaveToken(url, token)
+	case TokenBackendFile:
+		return saveFileToken(url, token)
+	case TokenBackendNetrc, TokenBackendCommand:
+		return fmt.Errorf("the %s token backend is read-only", backend)
+	default:
+		return fmt.Errorf("unknown token backend: %s", backend)
+	}
+}
+
+// LoadTokenFrom loads the token for a URL from a token backend. The command is only used by the command backend.
+func LoadTokenFrom(backend, command, url string) (string, error) {
+	switch backend {
+	case "", TokenBackendKeyring:
+		return LoadToken(url)
+	case TokenBackendFile:
+		return loadFileToken(url)
+	case TokenBackendNetrc:
+		return loadNetrcToken(url)
+	case TokenBackendCommand:
+		return runTokenCommand(command)
+	default:
+		return "", fmt.Errorf("unknown token backend: %s", backend)
+	}
+}
+
+// DeleteTokenFrom deletes the token for a URL from a token backend. It is not an error if there is none, and
+// nothing is deleted from the read-only netrc and command backends.
+func DeleteTokenFrom(backend, url string) error {
+	switch backend {
+	case "", TokenBackendKeyring:
+		return DeleteToken(url)
+	case TokenBackendFile:
+		return deleteFileToken(url)
+	default:
+		return nil
+	}
+}
+
+// loadNetrcToken loads the password of the URL's host from the netrc file
+func loadNetrcToken(url string) (string, error) {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid Jenkins URL: %s", url)
+	}
+	entry, ok, err := lookupNetrc(u.Host)
+	if err != nil {
+		return "", err
+	}
+	if !ok || entry.password == "" {
+		return "", fmt.Errorf("no password for %s in the netrc file", u.Host)
+	}
+	return entry.password, nil
+}
+
+// runTokenCommand runs a shell command and returns its output, without surrounding whitespace, as the token.
+// Its stderr and stdin are the CLI's, so that a password manager can prompt.
+func runTokenCommand(command string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("no token command configured")
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	// The command may prompt for a passphrase, but only a terminal is passed on: stdin may be a piped token, or
+	// the JSON-RPC stream of the MCP server
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+	return token, nil
+}
+
+// tokenStore is the content of the encrypted token file. Each token is encrypted on its own, with its URL as
+// additional data so that it cannot be moved to another URL.
+type tokenStore struct {
+	// Salt is the salt of the key derived from JENKINS_TOKEN_PASSPHRASE, when one is used
+	Salt []byte `json:"salt"`
+	// Tokens are the nonce and ciphertext of each token, by URL
+	Tokens map[string][]byte `json:"tokens"`
+}
+
+// tokenFilePath returns the path of a file in the config directory
+func tokenFilePath(name string) (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), name), nil
+}
+
+func saveFileToken(url, token string) error {
+	store, err := readTokenStore()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	aead, err := tokenCipher(&store, true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	store.Tokens[url] = aead.Seal(nonce, nonce, []byte(token), []byte(url))
+	return writeTokenStore(store)
+}
+
+func loadFileToken(url string) (string, error) {
+	store, err := readTokenStore()
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := store.Tokens[url]
+	if !ok {
+		return "", fmt.Errorf("no token for %s in the token file", url)
+	}
+	aead, err := tokenCipher(&store, false)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("corrupt token for %s in the token file", url)
+	}
+	token, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(url))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the token for %s (is JENKINS_TOKEN_PASSPHRASE the one it was stored with?)", url)
+	}
+	return string(token), nil
+}
+
+func deleteFileToken(url string) error {
+	store, err := readTokenStore()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := store.Tokens[url]; !ok {
+		return nil
+	}
+	delete(store.Tokens, url)
+	return writeTokenStore(store)
+}
+
+// tokenCipher returns the cipher of the token file. The key is derived from the JENKINS_TOKEN_PASSPHRASE env var
+// if set, or else is a random key kept in its own file, which only protects the tokens if the token file is copied
+// without it. With create, a missing salt or key file is created. Tokens already stored are never left behind under
+// another key: a store holding tokens can only be added to with the key it was stored with.
+func tokenCipher(store *tokenStore, create bool) (cipher.AEAD, error) {
+	var key []byte
+	if passphrase := os.Getenv("JENKINS_TOKEN_PASSPHRASE"); passphrase != "" {
+		if len(store.Salt) == 0 {
+			if !create || len(store.Tokens) > 0 {
+				return nil, fmt.Errorf("the token file was not stored with a passphrase, unset JENKINS_TOKEN_PASSPHRASE (or run 'jenkins logout' for each of its tokens first)")
+			}
+			store.Salt = make([]byte, 16)
+			if _, err := rand.Read(store.Salt); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		key, err = pbkdf2.Key(sha256.New, passphrase, store.Salt, pbkdf2Iterations, 32)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if len(store.Salt) > 0 {
+			if !create || len(store.Tokens) > 0 {
+				return nil, fmt.Errorf("the token file was stored with a passphrase, set JENKINS_TOKEN_PASSPHRASE")
+			}
+			store.Salt = nil
+		}
+		var err error
+		key, err = readTokenKey(create)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readTokenKey reads the random key of the token file, creating it if asked to
+func readTokenKey(create bool) ([]byte, error) {
+	path, err := tokenFilePath(tokenKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write token key file: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid token key file: %s", path)
+	}
+	return key, nil
+}
+
+func readTokenStore() (tokenStore, error) {
+	store := tokenStore{Tokens: map[string][]byte{}}
+	path, err := tokenFilePath(tokenFile)
+	if err != nil {
+		return store, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return store, fmt.Errorf("failed to read token file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return store, fmt.Errorf("failed to parse token file: %w", err)
+	}
+	if store.Tokens == nil {
+		store.Tokens = map[string][]byte{}
+	}
+	return store, nil
+}
+
+func writeTokenStore(store tokenStore) error {
+	path, err := tokenFilePath(tokenFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token file: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// TestFileTokenBackend tests storing tokens in the encrypted file, with a key file or a passphrase
+func TestFileTokenBackend(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "")
+
+	if err := StoreToken(TokenBackendFile, "https://a.example.com", "token-a"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	if err := StoreToken(TokenBackendFile, "https://b.example.com", "token-b"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	for url, want := range map[string]string{"https://a.example.com": "token-a", "https://b.example.com": "token-b"} {
+		if token, err := LoadTokenFrom(TokenBackendFile, "", url); err != nil || token != want {
+			t.Errorf("Expected %q for %s, got %q (%v)", want, url, token, err)
+		}
+	}
+	if _, err := LoadTokenFrom(TokenBackendFile, "", "https://c.example.com"); err == nil {
+		t.Error("Expected an error for a URL without a token")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "jenkins-cli", tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "token-a") {
+		t.Errorf("Expected the token file to be encrypted:\n%s", data)
+	}
+	info, err := os.Stat(filepath.Join(dir, "jenkins-cli", tokenKeyFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file, got %v (%v)", info, err)
+	}
+
+	// With a passphrase, the token can only be read with the same passphrase
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "correct horse")
+	if err := StoreToken(TokenBackendFile, "https://a.example.com", "token-a"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	if token, err := LoadTokenFrom(TokenBackendFile, "", "https://a.example.com"); err != nil || token != "token-a" {
+		t.Errorf("Expected token-a, got %q (%v)", token, err)
+	}
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "battery staple")
+	if _, err := LoadTokenFrom(TokenBackendFile, "", "https://a.example.com"); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Expected a decryption error, got: %v", err)
+	}
+}
+
+// TestFileTokenBackend_KeyChange tests that tokens already stored with one kind of key are not left undecryptable by
+// storing another token with the other kind
+func TestFileTokenBackend_KeyChange(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "")
+	if err := StoreToken(TokenBackendFile, "https://a.example.com", "token-a"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "correct horse")
+	if err := StoreToken(TokenBackendFile, "https://b.example.com", "token-b"); err == nil || !strings.Contains(err.Error(), "not stored with a passphrase") {
+		t.Errorf("Expected an error for a passphrase on a token file without one, got: %v", err)
+	}
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "")
+	if token, err := LoadTokenFrom(TokenBackendFile, "", "https://a.example.com"); err != nil || token != "token-a" {
+		t.Errorf("Expected token-a to be kept, got %q (%v)", token, err)
+	}
+
+	// Once its tokens are deleted, the token file can be used with a passphrase
+	if err := DeleteTokenFrom(TokenBackendFile, "https://a.example.com"); err != nil {
+		t.Fatalf("DeleteTokenFrom returned error: %v", err)
+	}
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "correct horse")
+	if err := StoreToken(TokenBackendFile, "https://b.example.com", "token-b"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "")
+	if err := StoreToken(TokenBackendFile, "https://c.example.com", "token-c"); err == nil || !strings.Contains(err.Error(), "stored with a passphrase") {
+		t.Errorf("Expected an error for no passphrase on a token file with one, got: %v", err)
+	}
+}
+
+// TestReadOnlyTokenBackends tests reading tokens from the netrc file and a command, which cannot store them
+func TestReadOnlyTokenBackends(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	if err := os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine jenkins.example.com login alice password netrc-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if token, err := LoadTokenFrom(TokenBackendNetrc, "", "https://jenkins.example.com:8443/ci"); err != nil || token != "netrc-token" {
+		t.Errorf("Expected netrc-token, got %q (%v)", token, err)
+	}
+	if _, err := LoadTokenFrom(TokenBackendNetrc, "", "https://other.example.com"); err == nil {
+		t.Error("Expected an error for a host without a netrc entry")
+	}
+
+	if token, err := LoadTokenFrom(TokenBackendCommand, "echo '  command-token  '", "https://jenkins.example.com"); err != nil || token != "command-token" {
+		t.Errorf("Expected command-token, got %q (%v)", token, err)
+	}
+	if _, err := LoadTokenFrom(TokenBackendCommand, "exit 3", "https://jenkins.example.com"); err == nil || !strings.Contains(err.Error(), "token command failed") {
+		t.Errorf("Expected a command error, got: %v", err)
+	}
+
+	for _, backend := range []string{TokenBackendNetrc, TokenBackendCommand} {
+		if err := StoreToken(backend, "https://jenkins.example.com", "token"); err == nil || !strings.Contains(err.Error(), "read-only") {
+			t.Errorf("Expected a read-only error for %s, got: %v", backend, err)
+		}
+	}
+}
+
+// TestResolve_TokenBackend tests that the token comes from the context's token backend
+func TestResolve_TokenBackend(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_URL", "")
+	t.Setenv("JENKINS_TOKEN", "")
+
+	settings := Context{URL: "https://jenkins.example.com", Username: "alice", TokenBackend: TokenBackendCommand, TokenCommand: "echo from-password-manager"}
+	if _, err := SaveContext(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if creds.Token != "from-password-manager" || creds.TokenSource != SourceCommand {
+		t.Errorf("Unexpected token %q from %q", creds.Token, creds.TokenSource)
+	}
+
+	settings.TokenCommand = "false"
+	if _, err := SaveContext(settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve(Overrides{}); err == nil || !strings.Contains(err.Error(), "command backend") {
+		t.Errorf("Expected the token command's failure, got: %v", err)
+	}
+}
+
+// TestDeleteTokenFrom tests deleting tokens from the keyring and the encrypted file
+func TestDeleteTokenFrom(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("JENKINS_TOKEN_PASSPHRASE", "")
+
+	for _, backend := range []string{TokenBackendKeyring, TokenBackendFile} {
+		if err := StoreToken(backend, "https://jenkins.example.com", "secret"); err != nil {
+			t.Fatalf("StoreToken returned error: %v", err)
+		}
+		if err := DeleteTokenFrom(backend, "https://jenkins.example.com"); err != nil {
+			t.Fatalf("DeleteTokenFrom returned error: %v", err)
+		}
+		if _, err := LoadTokenFrom(backend, "", "https://jenkins.example.com"); err == nil {
+			t.Errorf("Expected the token to be deleted from %s", backend)
+		}
+		// Deleting again is not an error
+		if err := DeleteTokenFrom(backend, "https://jenkins.example.com"); err != nil {
+			t.Errorf("Expected deleting a missing token from %s to succeed, got: %v", backend, err)
+		}
+	}
+}
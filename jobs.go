@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bndr/gojenkins"
+)
+
+// listJobsConcurrency is the maximum number of folders fetched at once when listing jobs recursively
+const listJobsConcurrency = 8
+
+// jobListOptions selects the jobs returned by fetchJobList
+type jobListOptions struct {
+	// Recursive lists the jobs inside folders and multi-branch pipelines, by their full path
+	Recursive bool
+	// Depth limits how many folder levels are listed, top-level jobs being depth 1, and implies Recursive; zero is
+	// unlimited
+	Depth int
+	// Pattern selects jobs whose full path matches it, unless nil
+	Pattern *regexp.Regexp
+	// Statuses are the accepted statuses derived from the job color (e.g. FAILURE); empty accepts any
+	Statuses []string
+	// IncludeDisabled lists disabled jobs, which are hidden otherwise
+	IncludeDisabled bool
+	// Building selects only jobs with a build in progress
+	Building bool
+}
+
+// newJobListOptions parses the job listing options shared by the CLI and the MCP server.
+// pattern is a glob, or a regular expression when regex is true, and status is a comma-separated list of statuses.
+func newJobListOptions(recursive bool, depth int, pattern string, regex bool, status string, includeDisabled, building bool) (jobListOptions, error) {
+	if depth < 0 {
+		return jobListOptions{}, fmt.Errorf("depth must not be negative: %d", depth)
+	}
+	compiled, err := compileJobPattern(pattern, regex)
+	if err != nil {
+		return jobListOptions{}, err
+	}
+	statuses := parseStatusList(status)
+	return jobListOptions{
+		Recursive:       recursive || depth > 0,
+		Depth:           depth,
+		Pattern:         compiled,
+		Statuses:        statuses,
+		IncludeDisabled: includeDisabled || slices.Contains(statuses, "DISABLED"),
+		Building:        building,
+	}, nil
+}
+
+// compileJobPattern compiles a job name pattern. A glob matches the whole full path, with "*" matching
+// any characters including "/"; a glob without wildcards matches any path containing it. Globs ignore case.
+func compileJobPattern(pattern string, regex bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if regex {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return compiled, nil
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern)), nil
+	}
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob: unclosed '[' in %s", pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	compiled, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob: %s", pattern)
+	}
+	return compiled, nil
+}
+
+// matches reports whether a job, named by its full path, is selected by the options
+func (o jobListOptions) matches(job gojenkins.InnerJob) bool {
+	if !o.IncludeDisabled && strings.HasPrefix(job.Color, "disabled") {
+		return false
+	}
+	if o.Pattern != nil && !o.Pattern.MatchString(job.Name) {
+		return false
+	}
+	if len(o.Statuses) > 0 && !slices.Contains(o.Statuses, getStatusFromColor(job.Color)) {
+		return false
+	}
+	if o.Building && !isBuildingColor(job.Color) {
+		return false
+	}
+	return true
+}
+
+// fetchJobList lists the jobs selected by the options, optionally walking into folders
+func fetchJobList(ctx context.Context, client *gojenkins.Jenkins, opts jobListOptions) (*jobList, error) {
+	var jobs []gojenkins.InnerJob
+	if opts.Recursive {
+		var err error
+		jobs, err = walkJobs(ctx, client, opts.Depth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+	} else {
+		var err error
+		jobs, err = client.GetAllJobNames(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+	}
+
+	list := &jobList{Jobs: []jobSummary{}}
+	for _, job := range jobs {
+		if opts.matches(job) {
+			list.Jobs = append(list.Jobs, newJobSummary(job))
+		}
+	}
+	return list, nil
+}
+
+// walkJobs lists the jobs inside all folders by their full path, sorted by name
+func walkJobs(ctx context.Context, client *gojenkins.Jenkins, maxDepth int) ([]gojenkins.InnerJob, error) {
+	walker := &jobWalker{client: client, maxDepth: maxDepth, sem: make(chan struct{}, listJobsConcurrency)}
+	walker.sem <- struct{}{}
+	walker.walk(ctx, gojenkins.InnerJob{}, 1)
+	walker.wg.Wait()
+	if walker.err != nil {
+		return nil, walker.err
+	}
+
+	sort.Slice(walker.jobs, func(i, j int) bool { return walker.jobs[i].Name < walker.jobs[j].Name })
+	return walker.jobs, nil
+}
+
+// jobWalker walks the job tree concurrently, fetching at most cap(sem) folders at once
+type jobWalker struct {
+	client   *gojenkins.Jenkins
+	maxDepth int
+	sem      chan struct{}
+	wg       sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs []gojenkins.InnerJob
+	err  error
+}
+
+// walk lists the items in a folder (the top level when the folder has no name), recording jobs under their
+// full path and walking into sub-folders in the background. It is called holding a slot of sem, which it frees once
+// the folder is fetched, and a sub-folder is only walked once it has a slot. An item that turns out to have no
+// items list is a job without a color and is recorded, while an empty folder is skipped.
+func (w *jobWalker) walk(ctx context.Context, folder gojenkins.InnerJob, depth int) {
+	items, isFolder, err := w.listFolder(ctx, folder.Name)
+	<-w.sem
+
+	for _, item := range w.record(folder, items, isFolder, err, depth) {
+		w.sem <- struct{}{}
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.walk(ctx, item, depth+1)
+		}()
+	}
+}
+
+// record records the jobs in a folder at depth, or the error listing it, and returns its sub-folders to walk into
+func (w *jobWalker) record(folder gojenkins.InnerJob, items []gojenkins.InnerJob, isFolder bool, err error, depth int) []gojenkins.InnerJob {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return nil
+	}
+	if w.err != nil {
+		return nil
+	}
+	if !isFolder {
+		w.jobs = append(w.jobs, folder)
+		return nil
+	}
+
+	var folders []gojenkins.InnerJob
+	for _, item := range items {
+		if folder.Name != "" {
+			item.Name = folder.Name + "/" + item.Name
+		}
+		// Only folders, multi-branch pipelines and other containers have no color
+		if item.Color == "" && (w.maxDepth == 0 || depth < w.maxDepth) {
+			folders = append(folders, item)
+			continue
+		}
+		w.jobs = append(w.jobs, item)
+	}
+	return folders
+}
+
+// listFolder lists the items directly inside a folder, or at the top level when folder is empty. isFolder is false
+// for a job, which has no items list at all.
+func (w *jobWalker) listFolder(ctx context.Context, folder string) (items []gojenkins.InnerJob, isFolder bool, err error) {
+	path := "/"
+	if folder != "" {
+		path = jobPath(folder)
+	}
+	var resp struct {
+		Jobs *[]gojenkins.InnerJob `json:"jobs"`
+	}
+	if err := getJSON(ctx, w.client, path, &resp, map[string]string{"tree": "jobs[name,url,color]"}); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", strings.TrimPrefix(path, "/"), err)
+	}
+	if resp.Jobs == nil {
+		return nil, false, nil
+	}
+	return *resp.Jobs, true, nil
+}
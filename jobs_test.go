@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newTestJenkinsFolders serves a job tree with a folder containing a multi-branch pipeline, an empty folder and a job
+// without a color
+func newTestJenkinsFolders(t *testing.T) map[string]http.HandlerFunc {
+	jobs := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+	return map[string]http.HandlerFunc{
+		"/api/json/": jobs(`{"jobs":[
+			{"name":"app","url":"http://jenkins/job/app/","color":"blue"},
+			{"name":"team","url":"http://jenkins/job/team/"},
+			{"name":"empty","url":"http://jenkins/job/empty/"},
+			{"name":"external","url":"http://jenkins/job/external/"}
+		]}`),
+		"/job/team/api/json/": jobs(`{"jobs":[
+			{"name":"service","url":"http://jenkins/job/team/job/service/"},
+			{"name":"tool","url":"http://jenkins/job/team/job/tool/","color":"red"}
+		]}`),
+		"/job/team/job/service/api/json/": jobs(`{"jobs":[
+			{"name":"main","url":"http://jenkins/job/team/job/service/job/main/","color":"blue_anime"},
+			{"name":"old","url":"http://jenkins/job/team/job/service/job/old/","color":"disabled"}
+		]}`),
+		"/job/empty/api/json/":    jobs(`{"jobs":[]}`),
+		"/job/external/api/json/": jobs(`{}`),
+	}
+}
+
+// TestFetchJobList_Recursive tests listing jobs inside folders by their full path
+func TestFetchJobList_Recursive(t *testing.T) {
+	client := newTestJenkins(t, newTestJenkinsFolders(t))
+
+	tests := []struct {
+		depth    int
+		expected []string
+	}{
+		{0, []string{"app", "external", "team/service/main", "team/tool"}},
+		{2, []string{"app", "external", "team/service", "team/tool"}},
+		{1, []string{"app", "empty", "external", "team"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.expected, ","), func(t *testing.T) {
+			list, err := fetchJobList(context.Background(), client, jobListOptions{Recursive: true, Depth: tt.depth})
+			if err != nil {
+				t.Fatalf("fetchJobList returned error: %v", err)
+			}
+			var names []string
+			for _, job := range list.Jobs {
+				names = append(names, job.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("fetchJobList(depth %d) = %v, want %v", tt.depth, names, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNewJobListOptions_Depth tests that a depth implies listing recursively, rather than being ignored
+func TestNewJobListOptions_Depth(t *testing.T) {
+	opts, err := newJobListOptions(false, 2, "", false, "", false, false)
+	if err != nil || !opts.Recursive || opts.Depth != 2 {
+		t.Errorf("newJobListOptions(depth 2) = %+v (%v), want recursive to depth 2", opts, err)
+	}
+	if opts, _ := newJobListOptions(false, 0, "", false, "", false, false); opts.Recursive {
+		t.Error("Expected no recursion without recursive or a depth")
+	}
+}
+
+// TestFetchJobList_RecursiveError tests that a folder that cannot be listed fails the listing
+func TestFetchJobList_RecursiveError(t *testing.T) {
+	handlers := newTestJenkinsFolders(t)
+	handlers["/job/team/api/json/"] = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}
+	client := newTestJenkins(t, handlers)
+
+	_, err := fetchJobList(context.Background(), client, jobListOptions{Recursive: true})
+	if err == nil || !strings.Contains(err.Error(), "job/team") {
+		t.Errorf("Expected error listing job/team, got: %v", err)
+	}
+}
+
+// TestJobURLName tests converting full job paths to the form used in Jenkins URLs
+func TestJobURLName(t *testing.T) {
+	tests := map[string]string{
+		"app":                          "app",
+		"team/service/main":            "team/job/service/job/main",
+		"team/job/service/job/main":    "team/job/service/job/main",
+		"/team/service/":               "team/job/service",
+		"my-pipeline/job/feature%2Fui": "my-pipeline/job/feature%2Fui",
+	}
+	for name, expected := range tests {
+		if got := jobURLName(name); got != expected {
+			t.Errorf("jobURLName(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+// TestCompileJobPattern tests glob, substring and regular expression job patterns
+func TestCompileJobPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		regex   bool
+		name    string
+		matches bool
+	}{
+		{"deploy", false, "team/Deploy-prod", true},
+		{"deploy", false, "team/build", false},
+		{"team/*", false, "team/service/main", true},
+		{"team/*", false, "other/team/x", false},
+		{"*/main", false, "team/service/main", true},
+		{"service-?", false, "service-1", true},
+		{"service-[ab]", false, "service-b", true},
+		{"service-[!ab]", false, "service-b", false},
+		{"a.b", false, "axb", false},
+		{"^team/.*/main$", true, "team/service/main", true},
+		{"^team/.*/main$", true, "team/service/develop", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			re, err := compileJobPattern(tt.pattern, tt.regex)
+			if err != nil {
+				t.Fatalf("compileJobPattern(%q) returned error: %v", tt.pattern, err)
+			}
+			if got := re.MatchString(tt.name); got != tt.matches {
+				t.Errorf("pattern %q matching %q = %v, want %v", tt.pattern, tt.name, got, tt.matches)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"service-[ab", "("} {
+		if _, err := compileJobPattern(pattern, pattern == "("); err == nil {
+			t.Errorf("compileJobPattern(%q) expected error, got nil", pattern)
+		}
+	}
+}
+
+// TestFetchJobList_Filters tests filtering jobs by pattern, status, disabled state and building
+func TestFetchJobList_Filters(t *testing.T) {
+	client := newTestJenkins(t, newTestJenkinsFolders(t))
+
+	tests := []struct {
+		pattern         string
+		status          string
+		includeDisabled bool
+		building        bool
+		expected        []string
+	}{
+		{"service", "", false, false, []string{"team/service/main"}},
+		{"service", "", true, false, []string{"team/service/main", "team/service/old"}},
+		{"", "failure", false, false, []string{"team/tool"}},
+		{"", "DISABLED", false, false, []string{"team/service/old"}},
+		{"", "", false, true, []string{"team/service/main"}},
+		{"team/*", "SUCCESS,FAILURE", false, false, []string{"team/service/main", "team/tool"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.status, func(t *testing.T) {
+			opts, err := newJobListOptions(true, 0, tt.pattern, false, tt.status, tt.includeDisabled, tt.building)
+			if err != nil {
+				t.Fatalf("newJobListOptions returned error: %v", err)
+			}
+			list, err := fetchJobList(context.Background(), client, opts)
+			if err != nil {
+				t.Fatalf("fetchJobList returned error: %v", err)
+			}
+			var names []string
+			for _, job := range list.Jobs {
+				names = append(names, job.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("fetchJobList = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,647 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonPath is a parsed kubectl-style JSONPath template, e.g. "{range .jobs[*]}{.name}{'\n'}{end}".
+// It is evaluated against the JSON form of a result, so paths use the JSON field names.
+//
+// Supported syntax: literal text, "{.a.b}" field access, "{.a[0]}" indexes (negative counts from the end),
+// "{.a[1:3]}" slices, "{.a[*]}" and "{.a.*}" wildcards, "{..name}" and "{..[0]}" recursive descent,
+// "{.a[?(@.b=='x')]}" filters using ==, !=, <, <=, > or >=, "{range ...}...{end}" loops and
+// quoted string literals such as "{'\t'}".
+//
+// As in kubectl, a field that none of the values it is looked up in have, or an index beyond the end of an array,
+// is an error, while wildcards, slices and filters may select nothing, e.g. when no job has failed.
+type jsonPath struct {
+	nodes []jpNode
+}
+
+// jpNode is a node of a parsed template: literal text, an expression, or a range loop
+type jpNode struct {
+	text   string
+	path   []jpStep
+	isPath bool
+	isRng  bool
+	body   []jpNode
+}
+
+// jpStep is a single step in a path
+type jpStep struct {
+	kind      jpStepKind
+	name      string
+	index     int
+	start     *int
+	end       *int
+	filter    *jpFilter
+	recursive bool
+}
+
+type jpStepKind int
+
+const (
+	jpField jpStepKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpFilterStep
+	jpRoot
+)
+
+// jpFilter is a filter predicate such as "@.status=='FAILURE'"; with no op it tests that the path exists
+type jpFilter struct {
+	left  []jpStep
+	op    string
+	right any
+}
+
+// parseJSONPath parses a JSONPath template
+func parseJSONPath(template string) (*jsonPath, error) {
+	nodes, _, err := parseJPNodes(template, false)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonPath{nodes: nodes}, nil
+}
+
+// parseJPNodes parses nodes until the end of the template, or until {end} when inRange is true
+func parseJPNodes(s string, inRange bool) ([]jpNode, string, error) {
+	var nodes []jpNode
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			nodes = append(nodes, jpNode{text: s})
+			s = ""
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jpNode{text: s[:open]})
+		}
+		closeIdx, err := findClosingBrace(s, open)
+		if err != nil {
+			return nil, "", err
+		}
+		expr := strings.TrimSpace(s[open+1 : closeIdx])
+		s = s[closeIdx+1:]
+
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("unexpected {end}")
+			}
+			return nodes, s, nil
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJPPath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			body, rest, err := parseJPNodes(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jpNode{path: path, isRng: true, body: body})
+			s = rest
+			continue
+		case strings.HasPrefix(expr, "'") || strings.HasPrefix(expr, `"`):
+			text, err := unquoteJP(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jpNode{text: text})
+		default:
+			path, err := parseJPPath(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jpNode{path: path, isPath: true})
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("{range} without {end}")
+	}
+	return nodes, "", nil
+}
+
+// findClosingBrace returns the index of the brace closing the one at open, skipping quoted strings
+func findClosingBrace(s string, open int) (int, error) {
+	var quote byte
+	for i := open + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '}':
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unclosed '{' in %q", s[open:])
+}
+
+// unquoteJP unquotes a single- or double-quoted string literal
+func unquoteJP(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("invalid string literal: %s", s)
+		}
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`), `\'`, `'`) + `"`
+	}
+	text, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string literal: %s", s)
+	}
+	return text, nil
+}
+
+// parseJPPath parses a path expression such as ".jobs[0].name" or "$..number"
+func parseJPPath(s string) ([]jpStep, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	var steps []jpStep
+	switch s[0] {
+	case '$':
+		steps = append(steps, jpStep{kind: jpRoot})
+		s = s[1:]
+	case '@':
+		s = s[1:]
+	}
+
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "..["):
+			// A subscript of every nested value, e.g. "..[0]"
+			closeIdx, err := findClosingBracket(s[2:])
+			if err != nil {
+				return nil, err
+			}
+			step, err := parseJPSubscript(strings.TrimSpace(s[3 : closeIdx+2]))
+			if err != nil {
+				return nil, err
+			}
+			step.recursive = true
+			steps = append(steps, step)
+			s = s[closeIdx+3:]
+		case strings.HasPrefix(s, ".."):
+			name, rest := splitJPName(s[2:])
+			step := jpStep{kind: jpField, name: name, recursive: true}
+			switch {
+			case name == "*":
+				step.kind = jpWildcard
+			case name == "":
+				return nil, fmt.Errorf("missing field name after '..'")
+			case !validJPName(name):
+				return nil, fmt.Errorf("invalid field name: %s", name)
+			}
+			steps = append(steps, step)
+			s = rest
+		case s[0] == '.':
+			name, rest := splitJPName(s[1:])
+			switch name {
+			case "":
+				// A lone "." refers to the current value
+			case "*":
+				steps = append(steps, jpStep{kind: jpWildcard})
+			default:
+				if !validJPName(name) {
+					return nil, fmt.Errorf("invalid field name: %s", name)
+				}
+				steps = append(steps, jpStep{kind: jpField, name: name})
+			}
+			s = rest
+		case s[0] == '[':
+			closeIdx, err := findClosingBracket(s)
+			if err != nil {
+				return nil, err
+			}
+			step, err := parseJPSubscript(strings.TrimSpace(s[1:closeIdx]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			s = s[closeIdx+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in path", s)
+		}
+	}
+	return steps, nil
+}
+
+// splitJPName splits a field name off the start of s
+func splitJPName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// validJPName reports whether a field name can be used unquoted: other names must be quoted, as in "['a b']"
+func validJPName(name string) bool {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// findClosingBracket returns the index of the bracket closing the one at the start of s
+func findClosingBracket(s string) (int, error) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed '[' in %q", s)
+}
+
+// parseJPSubscript parses the contents of a [...] subscript
+func parseJPSubscript(s string) (jpStep, error) {
+	switch {
+	case s == "*":
+		return jpStep{kind: jpWildcard}, nil
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		filter, err := parseJPFilter(strings.TrimSpace(s[2 : len(s)-1]))
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpFilterStep, filter: filter}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := unquoteJP(s)
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpField, name: name}, nil
+	case strings.Contains(s, ":"):
+		parts := strings.SplitN(s, ":", 2)
+		step := jpStep{kind: jpSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return jpStep{}, fmt.Errorf("invalid slice: [%s]", s)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return jpStep{}, fmt.Errorf("invalid subscript: [%s]", s)
+		}
+		return jpStep{kind: jpIndex, index: n}, nil
+	}
+}
+
+// parseJPFilter parses a filter predicate such as "@.result=='FAILURE'" or "@.number>10"
+func parseJPFilter(s string) (*jpFilter, error) {
+	if i, op := findJPOperator(s); op != "" {
+		left, err := parseJPPath(strings.TrimSpace(s[:i]))
+		if err != nil {
+			return nil, err
+		}
+		right, err := parseJPLiteral(strings.TrimSpace(s[i+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+		return &jpFilter{left: left, op: op, right: right}, nil
+	}
+	left, err := parseJPPath(s)
+	if err != nil {
+		return nil, err
+	}
+	return &jpFilter{left: left}, nil
+}
+
+// findJPOperator returns the first comparison operator in s that is outside a quoted string
+func findJPOperator(s string) (int, string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+				if strings.HasPrefix(s[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return 0, ""
+}
+
+// parseJPLiteral parses the right-hand side of a filter comparison
+func parseJPLiteral(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		return unquoteJP(s)
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("invalid filter value: %s", s)
+}
+
+// execute evaluates the template against the JSON form of data and writes the output to w
+func (p *jsonPath) execute(w io.Writer, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var root any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return err
+	}
+	return executeJPNodes(w, p.nodes, root, root)
+}
+
+func executeJPNodes(w io.Writer, nodes []jpNode, root, current any) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRng:
+			values, err := evalJPPath(node.path, root, current)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				if err := executeJPNodes(w, node.body, root, v); err != nil {
+					return err
+				}
+			}
+		case node.isPath:
+			values, err := evalJPPath(node.path, root, current)
+			if err != nil {
+				return err
+			}
+			// Empty values are left out, so they don't leave stray separators
+			var texts []string
+			for _, v := range values {
+				text, err := formatJPValue(v)
+				if err != nil {
+					return err
+				}
+				if text != "" {
+					texts = append(texts, text)
+				}
+			}
+			if _, err := io.WriteString(w, strings.Join(texts, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evalJPPath returns every value the path selects, starting from current. It is an error for a field or index
+// to select nothing from the values it is applied to.
+func evalJPPath(steps []jpStep, root, current any) ([]any, error) {
+	values := []any{current}
+	for _, step := range steps {
+		var next []any
+		for _, v := range values {
+			next = append(next, evalJPStep(step, root, v)...)
+		}
+		if len(values) > 0 && len(next) == 0 {
+			switch step.kind {
+			case jpField:
+				return nil, fmt.Errorf("%s is not found", step.name)
+			case jpIndex:
+				return nil, fmt.Errorf("index %d is out of range", step.index)
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func evalJPStep(step jpStep, root, v any) []any {
+	if step.recursive {
+		var out []any
+		for _, d := range jpDescendants(v) {
+			nonRecursive := step
+			nonRecursive.recursive = false
+			out = append(out, evalJPStep(nonRecursive, root, d)...)
+		}
+		return out
+	}
+
+	switch step.kind {
+	case jpRoot:
+		return []any{root}
+	case jpField:
+		if m, ok := v.(map[string]any); ok {
+			if child, ok := m[step.name]; ok {
+				return []any{child}
+			}
+		}
+	case jpWildcard:
+		return jpChildren(v)
+	case jpIndex:
+		if a, ok := v.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				return []any{a[i]}
+			}
+		}
+	case jpSlice:
+		if a, ok := v.([]any); ok {
+			start, end := 0, len(a)
+			if step.start != nil {
+				start = clampJPIndex(*step.start, len(a))
+			}
+			if step.end != nil {
+				end = clampJPIndex(*step.end, len(a))
+			}
+			if start < end {
+				return a[start:end]
+			}
+		}
+	case jpFilterStep:
+		var out []any
+		for _, child := range jpChildren(v) {
+			if step.filter.matches(root, child) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func clampJPIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// jpChildren returns the elements of an array or the values of an object in key order
+func jpChildren(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		children := make([]any, len(keys))
+		for i, k := range keys {
+			children[i] = v[k]
+		}
+		return children
+	}
+	return nil
+}
+
+// jpDescendants returns v and all of its nested values
+func jpDescendants(v any) []any {
+	out := []any{v}
+	for _, child := range jpChildren(v) {
+		out = append(out, jpDescendants(child)...)
+	}
+	return out
+}
+
+func (f *jpFilter) matches(root, v any) bool {
+	// A value without the field does not match, rather than being an error
+	values, err := evalJPPath(f.left, root, v)
+	if err != nil {
+		return false
+	}
+	if f.op == "" {
+		return len(values) > 0
+	}
+	for _, left := range values {
+		if compareJP(left, f.op, f.right) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareJP compares a selected value with a literal, numerically when both are numbers
+func compareJP(left any, op string, right any) bool {
+	if n, ok := left.(json.Number); ok {
+		if r, ok := right.(float64); ok {
+			l, err := n.Float64()
+			if err != nil {
+				return false
+			}
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	return false
+}
+
+// formatJPValue formats a selected value: scalars as plain text, objects and arrays as JSON
+func formatJPValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestJSONPath tests JSONPath templates against the JSON form of a result
+func TestJSONPath(t *testing.T) {
+	jobs := &jobList{Jobs: []jobSummary{
+		{Name: "app", Status: "SUCCESS", URL: "https://jenkins.example.com/job/app/"},
+		{Name: "tests", Status: "FAILURE", URL: "https://jenkins.example.com/job/tests/"},
+		{Name: "deploy", Status: "UNSTABLE", URL: "https://jenkins.example.com/job/deploy/"},
+	}}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{.jobs[0].name}", "app"},
+		{"{.jobs[-1].name}", "deploy"},
+		{"{.jobs[*].name}", "app tests deploy"},
+		{"{.jobs[1:].name}", "tests deploy"},
+		{"{$.jobs[:1].status}", "SUCCESS"},
+		{"{..status}", "SUCCESS FAILURE UNSTABLE"},
+		{"{.jobs[?(@.status=='FAILURE')].name}", "tests"},
+		{"{.jobs[?(@.status!='SUCCESS')].name}", "tests deploy"},
+		{`{range .jobs[*]}{.name}={.status}{"\n"}{end}`, "app=SUCCESS\ntests=FAILURE\ndeploy=UNSTABLE\n"},
+		{"jobs: {.jobs[0]}", `jobs: {"name":"app","status":"SUCCESS","url":"https://jenkins.example.com/job/app/"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			path, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) returned error: %v", tt.template, err)
+			}
+			var buf bytes.Buffer
+			if err := path.execute(&buf, jobs); err != nil {
+				t.Fatalf("execute returned error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("execute(%q) = %q, want %q", tt.template, buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestJSONPath_Constructs tests each construct of the JSONPath grammar
+func TestJSONPath_Constructs(t *testing.T) {
+	data := json.RawMessage(`{
+		"name": "app",
+		"number": 42,
+		"building": false,
+		"description": null,
+		"display name": "App",
+		"builds": [
+			{"number": 40, "result": "SUCCESS", "causes": [{"user": "alice"}]},
+			{"number": 41, "result": "FAILURE", "building": true},
+			{"number": 42, "result": "SUCCESS", "causes": [{"user": "bob"}]}
+		]
+	}`)
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		// Literal text and string literals
+		{"plain text", "plain text"},
+		{"{'a\\tb'}", "a\tb"},
+		{`{"a\nb"}`, "a\nb"},
+		{"{'it\\'s'}", "it's"},
+		{`{'{"quoted"}'}`, `{"quoted"}`},
+		// Fields, the root and the current value
+		{"{.name}", "app"},
+		{"{$.name}", "app"},
+		{"{@.name}", "app"},
+		{"{.builds[0].result}", "SUCCESS"},
+		{"{['display name']}", "App"},
+		{`{.builds[0]["result"]}`, "SUCCESS"},
+		{"{.number} {.building} [{.description}]", "42 false []"},
+		{"{.builds[0].causes}", `[{"user":"alice"}]`},
+		{"{.}", `{"building":false,"builds":[{"causes":[{"user":"alice"}],"number":40,"result":"SUCCESS"},{"building":true,"number":41,"result":"FAILURE"},{"causes":[{"user":"bob"}],"number":42,"result":"SUCCESS"}],"description":null,"display name":"App","name":"app","number":42}`},
+		// Indexes and slices
+		{"{.builds[1].number}", "41"},
+		{"{.builds[-1].number}", "42"},
+		{"{.builds[1:].number}", "41 42"},
+		{"{.builds[:2].number}", "40 41"},
+		{"{.builds[0:1].number}", "40"},
+		{"{.builds[-2:].number}", "41 42"},
+		{"{.builds[5:].number}", ""},
+		// Wildcards
+		{"{.builds[*].number}", "40 41 42"},
+		{"{.builds[0].*}", `[{"user":"alice"}] 40 SUCCESS`},
+		{"{.builds[*].causes[*].user}", "alice bob"},
+		// Recursive descent
+		{"{..user}", "alice bob"},
+		{"{.builds..user}", "alice bob"},
+		{"{..causes[0].user}", "alice bob"},
+		{"{..[0].user}", "alice bob"},
+		{"{.builds[0]..*}", `[{"user":"alice"}] 40 SUCCESS {"user":"alice"} alice`},
+		{"{.builds[0]..*..*}", `{"user":"alice"} alice alice`},
+		// Filters
+		{"{.builds[?(@.result=='FAILURE')].number}", "41"},
+		{`{.builds[?(@.result!="FAILURE")].number}`, "40 42"},
+		{"{.builds[?(@.number<41)].number}", "40"},
+		{"{.builds[?(@.number<=41)].number}", "40 41"},
+		{"{.builds[?(@.number>41)].number}", "42"},
+		{"{.builds[?(@.number>=41)].number}", "41 42"},
+		{"{.builds[?(@.building==true)].number}", "41"},
+		{"{.builds[?(@.causes)].number}", "40 42"},
+		{"{.builds[?(@.causes[0].user=='bob')].number}", "42"},
+		{"{.builds[?(@.result=='ABORTED')].number}", ""},
+		// Ranges
+		{"{range .builds[*]}{.number}:{.result} {end}", "40:SUCCESS 41:FAILURE 42:SUCCESS "},
+		{"{range .builds[?(@.causes)]}{range .causes[*]}{.user};{end}{end}", "alice;bob;"},
+		{"{range .builds[?(@.result=='ABORTED')]}{.number}{end}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			path, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) returned error: %v", tt.template, err)
+			}
+			var buf bytes.Buffer
+			if err := path.execute(&buf, data); err != nil {
+				t.Fatalf("execute returned error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("execute(%q) = %q, want %q", tt.template, buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestJSONPath_NotFound tests that fields and indexes selecting nothing are errors, as in kubectl
+func TestJSONPath_NotFound(t *testing.T) {
+	jobs := &jobList{Jobs: []jobSummary{{Name: "app", Status: "SUCCESS"}}}
+	tests := []struct {
+		template string
+		wantErr  string
+	}{
+		{"{.missing}", "missing is not found"},
+		{"{.jobs[0].missing}", "missing is not found"},
+		{"{..missing}", "missing is not found"},
+		{"{.jobs[3].name}", "index 3 is out of range"},
+		{"{.jobs[-2].name}", "index -2 is out of range"},
+		{"{range .jobs[*]}{.name}{.missing}{end}", "missing is not found"},
+		{"{range .missing[*]}{end}", "missing is not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			path, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) returned error: %v", tt.template, err)
+			}
+			var buf bytes.Buffer
+			if err := path.execute(&buf, jobs); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("execute(%q) = %v, want %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestJSONPath_NumericFilter tests that filters compare numbers numerically
+func TestJSONPath_NumericFilter(t *testing.T) {
+	job := &jobDetail{
+		Name:            "app",
+		LastBuild:       &buildRef{Number: 42, Result: "SUCCESS"},
+		LastFailedBuild: &buildRef{Number: 7, Result: "FAILURE"},
+	}
+	path, err := parseJSONPath("{$[?(@.number>9)].result}")
+	if err != nil {
+		t.Fatalf("parseJSONPath returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := path.execute(&buf, job); err != nil {
+		t.Fatalf("execute returned error: %v", err)
+	}
+	if buf.String() != "SUCCESS" {
+		t.Errorf("execute = %q, want %q", buf.String(), "SUCCESS")
+	}
+}
+
+// TestJSONPath_EmptyValues tests that empty and null values are left out when a path selects several values
+func TestJSONPath_EmptyValues(t *testing.T) {
+	data := json.RawMessage(`{"jobs": [
+		{"name": "app", "description": ""},
+		{"name": "tests", "description": null},
+		{"name": "deploy", "description": "Deploys the app"},
+		{"name": "docs", "description": "Builds the docs"}
+	]}`)
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{..description}", "Deploys the app Builds the docs"},
+		{"{.jobs[*].description}", "Deploys the app Builds the docs"},
+		{"{.jobs[:2].description}", ""},
+		{"{range .jobs[*]}{.name}:{.description};{end}", "app:;tests:;deploy:Deploys the app;docs:Builds the docs;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			path, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) returned error: %v", tt.template, err)
+			}
+			var buf bytes.Buffer
+			if err := path.execute(&buf, data); err != nil {
+				t.Fatalf("execute returned error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("execute(%q) = %q, want %q", tt.template, buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseJSONPath_Invalid tests that malformed templates are rejected
+func TestParseJSONPath_Invalid(t *testing.T) {
+	for _, template := range []string{"{.jobs", "{.jobs[0}", "{range .jobs[*]}{.name}", "{end}", "{.jobs[x]}", "{'unterminated}", "{..}", "{..[0}", "{.jobs[?(@.status=~'x')]}", "{.jobs[1:x]}", "{.a b}"} {
+		t.Run(template, func(t *testing.T) {
+			if _, err := parseJSONPath(template); err == nil {
+				t.Errorf("parseJSONPath(%q) expected error, got nil", template)
+			}
+		})
+	}
+}
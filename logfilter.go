@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// errorLinePattern matches lines reporting an error, e.g. "ERROR: ...", "[ERROR] ...", "npm ERR!" or "BUILD FAILURE"
+var errorLinePattern = regexp.MustCompile(`(?i)(^|[\s\[])(error|fatal|severe)(\]|:)|\w+(exception|error): |^panic: |^traceback \(most recent call last\)|npm err!|build failure|^finished: (failure|unstable|aborted)`)
+
+// stackTracePattern matches the lines of Java, Python and Go stack traces
+var stackTracePattern = regexp.MustCompile(`^\s+at \S+\(.*\)$|^\s+\.\.\. \d+ more$|^Caused by: |^\s+File ".*", line \d+|^goroutine \d+ \[|^\s+\S+\.go:\d+`)
+
+// exitCodePattern matches markers of a command exiting with a non-zero status, e.g. "script returned exit code 1"
+var exitCodePattern = regexp.MustCompile(`(?i)(exit (code|status)|exited with( code| status)?|returned exit code) [1-9]\d*`)
+
+// pipelineStepPattern matches the line announcing each pipeline step, e.g. "[Pipeline] sh"
+var pipelineStepPattern = regexp.MustCompile(`^\[Pipeline\] `)
+
+// errorStepLines is how many lines of a failing step's output are kept before a non-zero exit marker
+const errorStepLines = 30
+
+// logFilter selects the parts of a build log to show
+type logFilter struct {
+	// Head keeps only the first lines, unless zero
+	Head int
+	// Tail keeps only the last lines, unless zero
+	Tail int
+	// Grep keeps only the lines matching it, unless nil
+	Grep *regexp.Regexp
+	// Errors keeps only the lines that look like errors: error messages, stack traces, and the output of
+	// failing steps before their non-zero exit marker
+	Errors bool
+	// Context is the number of lines kept around each line selected by Grep or Errors
+	Context int
+}
+
+// newLogFilter parses the log filtering options shared by the CLI and the MCP server
+func newLogFilter(head, tail int, grep string, errors bool, context int) (logFilter, error) {
+	if head < 0 || tail < 0 || context < 0 {
+		return logFilter{}, fmt.Errorf("head, tail and context must not be negative")
+	}
+	if head > 0 && tail > 0 {
+		return logFilter{}, fmt.Errorf("head and tail cannot be combined")
+	}
+	filter := logFilter{Head: head, Tail: tail, Errors: errors, Context: context}
+	if grep != "" {
+		compiled, err := regexp.Compile(grep)
+		if err != nil {
+			return logFilter{}, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		filter.Grep = compiled
+	}
+	return filter, nil
+}
+
+// active reports whether the filter changes the log at all
+func (f logFilter) active() bool {
+	return f.Head > 0 || f.Tail > 0 || f.Grep != nil || f.Errors
+}
+
+// apply returns the selected parts of a log. Lines selected by Grep or Errors are kept with their context,
+// non-adjacent blocks being separated by "--" as in grep; Head or Tail then apply to the result.
+func (f logFilter) apply(log string) string {
+	if !f.active() || log == "" {
+		return log
+	}
+	out, _ := f.read(strings.NewReader(log))
+	return out
+}
+
+// read reads a log and returns the parts selected by the filter, as apply does. Only the lines still needed to
+// select others are kept in memory besides the result, and with Head reading stops once the first lines are read.
+func (f logFilter) read(r io.Reader) (string, error) {
+	var out []string
+	keep := func(lines ...string) bool {
+		for _, line := range lines {
+			if f.Head > 0 && len(out) == f.Head {
+				return false
+			}
+			out = append(out, line)
+			if f.Tail > 0 && len(out) > f.Tail {
+				out = out[1:]
+			}
+		}
+		return f.Head == 0 || len(out) < f.Head
+	}
+	if _, err := f.scan(r, keep); err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// scan reads the lines of a log, passing those selected by Grep or Errors to keep, or all of them if neither is
+// set. keep is passed a line, preceded by a "--" separator if it does not follow the line kept before, and returns
+// false to stop. scan returns how many bytes of the log it has decided on, which is where to continue from once
+// stopped, as lines are decided on only once the lines after them that may select them have been read.
+func (f logFilter) scan(r io.Reader, keep func(lines ...string) bool) (int64, error) {
+	s := &lineSelector{filter: f, last: -1, keep: keep}
+	if f.Grep != nil || f.Errors {
+		s.delay = f.Context
+		if f.Errors {
+			s.delay += errorStepLines
+		}
+	}
+
+	br := bufio.NewReader(r)
+	var end int64
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			end += int64(len(line))
+			if !s.add(strings.TrimSuffix(line, "\n"), end) {
+				return s.decidedEnd, nil
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return s.decidedEnd, err
+		}
+	}
+	s.decide(s.start + len(s.window))
+	return s.decidedEnd, nil
+}
+
+// lineSelector selects the lines of a log as they are read
+type lineSelector struct {
+	filter logFilter
+	// delay is how many lines after a line may select it, by context or as the output of a failing step
+	delay int
+	// window holds the lines not yet decided on, preceded by the decided lines they may need as context
+	window []selectedLine
+	// start is the index of the first line in window, and decided of the first line not decided on
+	start, decided int
+	// decidedEnd is the end offset of the last line decided on
+	decidedEnd int64
+	// last is the index of the last line kept, or -1
+	last int
+	keep func(lines ...string) bool
+}
+
+// selectedLine is a line of the log, and whether it is selected
+type selectedLine struct {
+	text     string
+	end      int64
+	selected bool
+}
+
+// matches reports whether a line is selected on its own
+func (s *lineSelector) matches(line string) bool {
+	f := s.filter
+	if f.Grep == nil && !f.Errors {
+		return true
+	}
+	if f.Grep != nil && f.Grep.MatchString(line) {
+		return true
+	}
+	return f.Errors && (errorLinePattern.MatchString(line) || stackTracePattern.MatchString(line) || exitCodePattern.MatchString(line))
+}
+
+// add adds the next line of the log, ending at offset end, and decides on the lines it was the last one needed for.
+// It returns false once keep does.
+func (s *lineSelector) add(text string, end int64) bool {
+	i := s.start + len(s.window)
+	s.window = append(s.window, selectedLine{text: text, end: end, selected: s.matches(text)})
+	if s.filter.Errors && exitCodePattern.MatchString(text) {
+		// Keep the failing step's output, back to the step's announcement
+		for j := i - 1; j >= s.decided && i-j <= errorStepLines; j-- {
+			s.window[j-s.start].selected = true
+			if pipelineStepPattern.MatchString(s.window[j-s.start].text) {
+				break
+			}
+		}
+	}
+	return s.decide(i + 1 - s.delay)
+}
+
+// decide decides on the lines before index until, keeping those that are selected or within Context lines of a
+// selected line. It returns false once keep does.
+func (s *lineSelector) decide(until int) bool {
+	for ; s.decided < until; s.decided++ {
+		j := s.decided
+		if s.withinContext(j) {
+			lines := []string{s.window[j-s.start].text}
+			if s.last >= 0 && j > s.last+1 {
+				lines = append([]string{"--"}, lines...)
+			}
+			if !s.keep(lines...) {
+				return false
+			}
+			s.last = j
+		}
+		s.decidedEnd = s.window[j-s.start].end
+	}
+	// Forget the lines no longer needed as context
+	if drop := s.decided - s.filter.Context - s.start; drop > 0 {
+		s.window = s.window[drop:]
+		s.start += drop
+	}
+	return true
+}
+
+// withinContext reports whether a line is selected or within Context lines of a selected line
+func (s *lineSelector) withinContext(i int) bool {
+	for j := max(s.start, i-s.filter.Context); j <= min(s.start+len(s.window)-1, i+s.filter.Context); j++ {
+		if s.window[j-s.start].selected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// testLog is a pipeline log with a failing shell step and a Java stack trace
+const testLog = `Started by user alice
+[Pipeline] stage
+[Pipeline] { (Build)
+[Pipeline] sh
++ make build
+go build ./...
+[Pipeline] }
+[Pipeline] stage
+[Pipeline] { (Test)
+[Pipeline] sh
++ make test
+--- FAIL: TestApp (0.01s)
+FAIL
+make: *** [test] Error 1
+ERROR: script returned exit code 2
+[Pipeline] }
+Exception in thread "main" java.lang.IllegalStateException: boom
+	at com.example.App.main(App.java:10)
+	... 3 more
+Caused by: java.io.IOException: disk full
+Some unrelated line
+Finished: FAILURE
+`
+
+func TestLogFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		head    int
+		tail    int
+		grep    string
+		errors  bool
+		context int
+		want    string
+	}{
+		{name: "none", want: testLog},
+		{name: "head", head: 2, want: "Started by user alice\n[Pipeline] stage\n"},
+		{name: "tail", tail: 2, want: "Some unrelated line\nFinished: FAILURE\n"},
+		{name: "grep", grep: `^\+ make`, want: "+ make build\n--\n+ make test\n"},
+		{name: "grep with context", grep: `make build`, context: 1, want: "[Pipeline] sh\n+ make build\ngo build ./...\n"},
+		{name: "grep with tail", grep: `Pipeline\] sh`, tail: 1, want: "[Pipeline] sh\n"},
+		{name: "no match", grep: `nothing`, want: ""},
+		{name: "errors", errors: true, want: `[Pipeline] sh
++ make test
+--- FAIL: TestApp (0.01s)
+FAIL
+make: *** [test] Error 1
+ERROR: script returned exit code 2
+--
+Exception in thread "main" java.lang.IllegalStateException: boom
+	at com.example.App.main(App.java:10)
+	... 3 more
+Caused by: java.io.IOException: disk full
+--
+Finished: FAILURE
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newLogFilter(tt.head, tt.tail, tt.grep, tt.errors, tt.context)
+			if err != nil {
+				t.Fatalf("newLogFilter returned error: %v", err)
+			}
+			if got := filter.apply(testLog); got != tt.want {
+				t.Errorf("apply() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogFilter_Invalid(t *testing.T) {
+	if _, err := newLogFilter(1, 1, "", false, 0); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("Expected error combining head and tail, got: %v", err)
+	}
+	if _, err := newLogFilter(0, 0, "(", false, 0); err == nil {
+		t.Error("Expected error for invalid regular expression")
+	}
+	if _, err := newLogFilter(-1, 0, "", false, 0); err == nil {
+		t.Error("Expected error for negative head")
+	}
+}
+
+// TestLogFilter_Read tests that reading stops once the lines for head are read, and that read errors are returned
+// rather than the lines read so far
+func TestLogFilter_Read(t *testing.T) {
+	errBroken := errors.New("connection reset")
+	broken := func() io.Reader {
+		return io.MultiReader(strings.NewReader(testLog), iotest.ErrReader(errBroken))
+	}
+
+	head, _ := newLogFilter(2, 0, "", false, 0)
+	if got, err := head.read(broken()); err != nil || got != "Started by user alice\n[Pipeline] stage\n" {
+		t.Errorf("read() with head = %q, %v", got, err)
+	}
+
+	grep, _ := newLogFilter(0, 0, "make", false, 0)
+	if got, err := grep.read(broken()); !errors.Is(err, errBroken) || got != "" {
+		t.Errorf("read() with a broken log = %q, %v, want the read error", got, err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// defaultLogLimit is the most log text returned by the get_build_log MCP tool at once, unless it asks for another limit
+const defaultLogLimit = 64 * 1024
+
+// logPage is a byte range of the console output of a build
+type logPage struct {
+	Job    string `json:"job"`
+	Number int64  `json:"number"`
+	// Offset is where the page starts in the log
+	Offset int64 `json:"offset"`
+	// NextOffset is where the next page starts, to be passed back as the offset to continue reading
+	NextOffset int64 `json:"nextOffset"`
+	// TotalSize is the size of the log so far, in bytes
+	TotalSize int64 `json:"totalSize"`
+	// Complete is true when the page reaches the end of the log and the build will not write any more
+	Complete bool `json:"complete"`
+	// Log is the text of the page, returned as text content rather than structured content
+	Log string `json:"-"`
+}
+
+func (p *logPage) writeText(w io.Writer) {
+	fmt.Fprint(w, p.Log)
+	if p.Log != "" && p.Log[len(p.Log)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+	switch {
+	case p.Complete:
+		fmt.Fprintf(w, "[end of log, %d bytes]\n", p.TotalSize)
+	case p.NextOffset >= p.TotalSize:
+		fmt.Fprintf(w, "[end of log so far, %d bytes; the build is still running, continue with offset=%d]\n", p.TotalSize, p.NextOffset)
+	default:
+		fmt.Fprintf(w, "[bytes %d-%d of %d; continue with offset=%d]\n", p.Offset, p.NextOffset, p.TotalSize, p.NextOffset)
+	}
+}
+
+// fetchLogPage gets up to limit bytes of the console output of a build using progressiveText, starting at offset,
+// or with fromEnd ending at the end of the log. Pages are cut at a line end when possible, and a page ending at the
+// log's end starts at a line start. Jenkins strips console notes from progressiveText, so the offset of a page that
+// is cut short counts the text returned: it may lag the raw offset, and the next page repeat a little text, but no
+// text is ever skipped.
+func fetchLogPage(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string, offset, limit int64, fromEnd bool) (*logPage, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("offset must not be negative and limit must be positive")
+	}
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+	path := build.Base + "/logText/progressiveText"
+
+	if fromEnd {
+		size, err := readLogSize(ctx, client, path)
+		if err != nil {
+			return nil, err
+		}
+		offset = max(0, size-limit)
+	}
+
+	info, data, err := readLogRange(ctx, client, path, offset, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if offset > info.size {
+		return nil, fmt.Errorf("offset %d is beyond the end of the log (%d bytes)", offset, info.size)
+	}
+
+	page := &logPage{Job: jobName, Number: build.GetBuildNumber(), Offset: offset, TotalSize: info.size}
+	truncated := int64(len(data)) > limit
+	if truncated {
+		data = data[:limit]
+		if end := bytes.LastIndexByte(data, '\n'); end >= 0 {
+			data = data[:end+1]
+		}
+		page.NextOffset = offset + int64(len(data))
+	} else {
+		page.NextOffset = info.size
+	}
+	if fromEnd && offset > 0 {
+		// Drop the partial first line
+		if start := bytes.IndexByte(data, '\n'); start >= 0 {
+			page.Offset += int64(start + 1)
+			data = data[start+1:]
+		}
+	}
+	page.Log = string(data)
+	page.Complete = !truncated && !info.more
+	return page, nil
+}
+
+// fetchFilteredLogPage gets the lines of the console output of a build selected by the filter's Grep or Errors.
+// Rather than filtering a single page, it reads the log from offset until it has collected up to limit bytes of
+// selected lines, or Head lines, or the log ends, so that an error at the end of a large log is found. A page that
+// is cut short continues from the end of the last line it decided on. With Tail, the log is read to its end and only
+// its last selected lines are returned, at most limit bytes of them. Lines selected by context or as a failing step's output
+// are only found within the part of the log read.
+func fetchFilteredLogPage(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string, offset, limit int64, filter logFilter) (*logPage, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("offset must not be negative and limit must be positive")
+	}
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := openStream(ctx, client, build.Base+"/logText/progressiveText?start="+strconv.FormatInt(offset, 10), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build log: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get build log: %w", newStatusError(resp))
+	}
+	size, err := parseLogSize(resp)
+	if err != nil {
+		return nil, err
+	}
+	if offset > size {
+		return nil, fmt.Errorf("offset %d is beyond the end of the log (%d bytes)", offset, size)
+	}
+
+	var out []string
+	var n int64
+	stopped := false
+	keep := func(lines ...string) bool {
+		var added int64
+		for _, line := range lines {
+			added += int64(len(line)) + 1
+		}
+		if filter.Tail == 0 && len(out) > 0 && (n+added > limit || filter.Head > 0 && len(out) >= filter.Head) {
+			stopped = true
+			return false
+		}
+		out, n = append(out, lines...), n+added
+		// Keep the last lines, dropping the first ones beyond Tail or limit
+		for filter.Tail > 0 && len(out) > 1 && (len(out) > filter.Tail || n > limit) {
+			n -= int64(len(out[0])) + 1
+			out = out[1:]
+		}
+		return true
+	}
+	decided, err := filter.scan(resp.Body, keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build log: %w", err)
+	}
+
+	page := &logPage{Job: jobName, Number: build.GetBuildNumber(), Offset: offset, TotalSize: size}
+	if stopped {
+		page.NextOffset = offset + decided
+	} else {
+		page.NextOffset = size
+		page.Complete = resp.Header.Get("X-More-Data") == ""
+	}
+	if len(out) > 0 {
+		page.Log = strings.Join(out, "\n") + "\n"
+	}
+	return page, nil
+}
+
+// logRangeInfo is what progressiveText reports about the log besides its text
+type logRangeInfo struct {
+	// size is the size of the log so far
+	size int64
+	// more is true while the build may still write to the log
+	more bool
+}
+
+// readLogRange reads up to limit bytes of the log from progressiveText starting at offset, without reading the rest
+func readLogRange(ctx context.Context, client *gojenkins.Jenkins, path string, offset, limit int64) (logRangeInfo, []byte, error) {
+	resp, err := openStream(ctx, client, path+"?start="+strconv.FormatInt(offset, 10), nil)
+	if err != nil {
+		return logRangeInfo{}, nil, fmt.Errorf("failed to get build log: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return logRangeInfo{}, nil, fmt.Errorf("failed to get build log: %w", newStatusError(resp))
+	}
+
+	size, err := parseLogSize(resp)
+	if err != nil {
+		return logRangeInfo{}, nil, err
+	}
+	info := logRangeInfo{size: size, more: resp.Header.Get("X-More-Data") != ""}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return logRangeInfo{}, nil, fmt.Errorf("failed to get build log: %w", err)
+	}
+	return info, data, nil
+}
+
+// readLogSize returns the size of the log so far. It asks progressiveText with a HEAD request, as Jenkins sends the
+// whole log for any start it accepts, restarting from 0 for a start beyond the end.
+func readLogSize(ctx context.Context, client *gojenkins.Jenkins, path string) (int64, error) {
+	resp, err := sendRequest(ctx, client, http.MethodHead, path+"?start=0", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get build log: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to get build log: %w", newStatusError(resp))
+	}
+	return parseLogSize(resp)
+}
+
+// parseLogSize returns the size of the log so far from a progressiveText response
+func parseLogSize(resp *http.Response) (int64, error) {
+	size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get build log: invalid X-Text-Size header")
+	}
+	return size, nil
+}
+
+// tailChunk is how much of the end of the log is read at first for each line wanted by readLogTail
+const tailChunk = 256
+
+// readLog reads the whole log from progressiveText, keeping only the parts selected by the filter
+func readLog(ctx context.Context, client *gojenkins.Jenkins, path string, filter logFilter) (string, error) {
+	resp, err := openStream(ctx, client, path+"?start=0", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get build log: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get build log: %w", newStatusError(resp))
+	}
+
+	var log string
+	if filter.active() {
+		log, err = filter.read(resp.Body)
+	} else {
+		var data []byte
+		data, err = io.ReadAll(resp.Body)
+		log = string(data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get build log: %w", err)
+	}
+	return log, nil
+}
+
+// readLogTail reads the last lines of the log from progressiveText, reading back from its end until it has them
+func readLogTail(ctx context.Context, client *gojenkins.Jenkins, path string, lines int) (string, error) {
+	size, err := readLogSize(ctx, client, path)
+	if err != nil {
+		return "", err
+	}
+	tail := logFilter{Tail: lines}
+	for chunk := int64(lines) * tailChunk; ; chunk *= 4 {
+		offset := max(0, size-chunk)
+		_, data, err := readLogRange(ctx, client, path, offset, size-offset)
+		if err != nil {
+			return "", err
+		}
+		if offset > 0 {
+			// Drop the partial first line, and read further back unless enough lines are left
+			start := bytes.IndexByte(data, '\n')
+			if start < 0 || bytes.Count(data[start+1:], []byte("\n")) < lines {
+				continue
+			}
+			data = data[start+1:]
+		}
+		return tail.apply(string(data)), nil
+	}
+}
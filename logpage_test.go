@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bndr/gojenkins"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pageTestLog is the log served by newLogPageTestJenkins, 10 lines of 7 bytes
+var pageTestLog = func() string {
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		b.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	return b.String()
+}()
+
+// newLogPageTestJenkins serves build #7 of app whose log is pageTestLog, still being written if running is true
+func newLogPageTestJenkins(t *testing.T, running bool) *gojenkins.Jenkins {
+	t.Helper()
+	return newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"building":` + strconv.FormatBool(running) + `}`))
+		},
+		"/job/app/7/logText/progressiveText": func(w http.ResponseWriter, r *http.Request) {
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			// Like Jenkins, a start beyond the end restarts from the beginning
+			if start > len(pageTestLog) {
+				start = 0
+			}
+			w.Header().Set("X-Text-Size", strconv.Itoa(len(pageTestLog)))
+			if running {
+				w.Header().Set("X-More-Data", "true")
+			}
+			w.Write([]byte(pageTestLog[start:]))
+		},
+	})
+}
+
+// TestFetchLogPage tests paging through a log, cutting pages at line ends
+func TestFetchLogPage(t *testing.T) {
+	client := newLogPageTestJenkins(t, false)
+	ctx := context.Background()
+
+	page, err := fetchLogPage(ctx, client, "app", "7", 0, 20, false)
+	if err != nil {
+		t.Fatalf("fetchLogPage returned error: %v", err)
+	}
+	if page.Log != "line 0\nline 1\n" || page.NextOffset != 14 || page.TotalSize != 70 || page.Complete {
+		t.Errorf("Unexpected first page: %+v (log %q)", page, page.Log)
+	}
+	if text := textOf(page); !strings.HasSuffix(text, "[bytes 0-14 of 70; continue with offset=14]") {
+		t.Errorf("Unexpected text: %q", text)
+	}
+
+	page, err = fetchLogPage(ctx, client, "app", "7", page.NextOffset, 100, false)
+	if err != nil {
+		t.Fatalf("fetchLogPage returned error: %v", err)
+	}
+	if page.Offset != 14 || page.NextOffset != 70 || !page.Complete || !strings.HasPrefix(page.Log, "line 2\n") {
+		t.Errorf("Unexpected last page: %+v (log %q)", page, page.Log)
+	}
+	if text := textOf(page); !strings.HasSuffix(text, "[end of log, 70 bytes]") {
+		t.Errorf("Unexpected text: %q", text)
+	}
+
+	if _, err := fetchLogPage(ctx, client, "app", "7", 100, 20, false); err == nil || !strings.Contains(err.Error(), "beyond the end") {
+		t.Errorf("Expected offset beyond the end error, got: %v", err)
+	}
+}
+
+// TestFetchLogPage_FromEnd tests reading the end of a log, starting at a line start
+func TestFetchLogPage_FromEnd(t *testing.T) {
+	client := newLogPageTestJenkins(t, true)
+
+	page, err := fetchLogPage(context.Background(), client, "app", "7", 0, 17, true)
+	if err != nil {
+		t.Fatalf("fetchLogPage returned error: %v", err)
+	}
+	if page.Log != "line 8\nline 9\n" || page.Offset != 56 || page.NextOffset != 70 || page.Complete {
+		t.Errorf("Unexpected page: %+v (log %q)", page, page.Log)
+	}
+	if text := textOf(page); !strings.Contains(text, "still running, continue with offset=70") {
+		t.Errorf("Unexpected text: %q", text)
+	}
+}
+
+// TestGetBuildLogHandler tests that the MCP tool returns a filtered page with its offsets as structured content
+func TestGetBuildLogHandler(t *testing.T) {
+	client := newLogPageTestJenkins(t, false)
+
+	result := callTestTool(t, client, "get_build_log", map[string]any{
+		"job_name":     "app",
+		"build_number": "7",
+		"tail":         2,
+		"grep":         "line [0-8]",
+	})
+	if result.IsError {
+		t.Fatalf("get_build_log failed: %+v", result)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(text, "line 7\nline 8\n[end of log") {
+		t.Errorf("Unexpected text: %q", text)
+	}
+	page, ok := result.StructuredContent.(*logPage)
+	if !ok || page.TotalSize != 70 || !page.Complete {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	result = callTestTool(t, client, "get_build_log", map[string]any{"job_name": "app", "build_number": "7", "tail": 2, "offset": 14})
+	if !result.IsError {
+		t.Errorf("Expected an error for offset with tail alone, got %+v", result)
+	}
+}
+
+// TestFetchLogPage_SizeProbe tests that reading the end of the log asks for its size without Jenkins sending it
+func TestFetchLogPage_SizeProbe(t *testing.T) {
+	var requests []string
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"building":false}`))
+		},
+		"/job/app/7/logText/progressiveText": func(w http.ResponseWriter, r *http.Request) {
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			requests = append(requests, r.Method+" "+strconv.Itoa(start))
+			w.Header().Set("X-Text-Size", strconv.Itoa(len(pageTestLog)))
+			w.Write([]byte(pageTestLog[start:]))
+		},
+	})
+
+	page, err := fetchLogPage(context.Background(), client, "app", "7", 0, 14, true)
+	if err != nil {
+		t.Fatalf("fetchLogPage returned error: %v", err)
+	}
+	if !strings.HasSuffix(page.Log, "line 9\n") {
+		t.Errorf("Unexpected log: %q", page.Log)
+	}
+	if want := []string{"HEAD 0", "GET 56"}; !slices.Equal(requests, want) {
+		t.Errorf("Expected requests %q, got %q", want, requests)
+	}
+}
+
+// TestFetchFilteredLogPage tests that filtering searches the log past the first page, until the limit of
+// matching lines is reached
+func TestFetchFilteredLogPage(t *testing.T) {
+	client := newLogPageTestJenkins(t, false)
+	ctx := context.Background()
+
+	last, _ := newLogFilter(0, 0, "line 9", false, 0)
+	page, err := fetchFilteredLogPage(ctx, client, "app", "7", 0, 20, last)
+	if err != nil {
+		t.Fatalf("fetchFilteredLogPage returned error: %v", err)
+	}
+	if page.Log != "line 9\n" || page.NextOffset != 70 || !page.Complete {
+		t.Errorf("Unexpected page: %+v (log %q)", page, page.Log)
+	}
+
+	many, _ := newLogFilter(0, 0, "line [2-9]", false, 0)
+	page, err = fetchFilteredLogPage(ctx, client, "app", "7", 0, 14, many)
+	if err != nil {
+		t.Fatalf("fetchFilteredLogPage returned error: %v", err)
+	}
+	if page.Log != "line 2\nline 3\n" || page.NextOffset != 28 || page.Complete {
+		t.Errorf("Unexpected first page: %+v (log %q)", page, page.Log)
+	}
+	page, err = fetchFilteredLogPage(ctx, client, "app", "7", page.NextOffset, 14, many)
+	if err != nil {
+		t.Fatalf("fetchFilteredLogPage returned error: %v", err)
+	}
+	if page.Log != "line 4\nline 5\n" || page.NextOffset != 42 {
+		t.Errorf("Unexpected second page: %+v (log %q)", page, page.Log)
+	}
+
+	tail, _ := newLogFilter(0, 3, "line [2-9]", false, 0)
+	page, err = fetchFilteredLogPage(ctx, client, "app", "7", 0, 14, tail)
+	if err != nil {
+		t.Fatalf("fetchFilteredLogPage returned error: %v", err)
+	}
+	if page.Log != "line 8\nline 9\n" || !page.Complete {
+		t.Errorf("Unexpected tail page: %+v (log %q)", page, page.Log)
+	}
+}
+
+// TestFetchBuildLog tests that the log is filtered as it is read, reading only its end for a tail
+func TestFetchBuildLog(t *testing.T) {
+	client := newLogPageTestJenkins(t, false)
+
+	tests := []struct {
+		head, tail int
+		grep       string
+		want       string
+	}{
+		{want: pageTestLog},
+		{tail: 2, want: "line 8\nline 9\n"},
+		{tail: 20, want: pageTestLog},
+		{head: 2, want: "line 0\nline 1\n"},
+		{grep: "line [37]", want: "line 3\n--\nline 7\n"},
+	}
+	for _, tt := range tests {
+		filter, err := newLogFilter(tt.head, tt.tail, tt.grep, false, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log, err := fetchBuildLog(context.Background(), client, "app", "7", filter)
+		if err != nil {
+			t.Fatalf("fetchBuildLog returned error: %v", err)
+		}
+		if log.Log != tt.want {
+			t.Errorf("fetchBuildLog(head %d, tail %d, grep %q) = %q, want %q", tt.head, tt.tail, tt.grep, log.Log, tt.want)
+		}
+	}
+}
@@ -1,344 +1,474 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/bndr/gojenkins"
-	"github.com/dustin/go-humanize"
 	"github.com/kitproj/jenkins-cli/internal/config"
 	"golang.org/x/term"
 )
 
 var (
 	url     string
-	token   string
 	user    string
+	output  string
 	jenkins *gojenkins.Jenkins
+	// allowWrite enables commands that change Jenkins state, which are disabled unless enabled here or in the config
+	allowWrite bool
+	// contextName selects the configured Jenkins server to use instead of the current context
+	contextName string
+	// credentials are those resolved for the command being run, so that they are resolved, and a token command
+	// run, only once
+	credentials *config.Credentials
 )
 
+// globalFlags are the flags that apply to every command. They may come before the command or, unless the command has
+// a flag of the same name, after it with its own flags.
+var globalFlags = flag.NewFlagSet("jenkins", flag.ContinueOnError)
+
+func init() {
+	globalFlags.Usage = func() {
+		printUsage(globalFlags.Output())
+	}
+	globalFlags.StringVar(&output, "output", "text", "Output format: text, json, yaml, junit (get-test-report only), go-template=..., go-template-file=..., jsonpath=... or jsonpath-file=...")
+	globalFlags.StringVar(&output, "o", "text", "Shorthand for -output")
+	globalFlags.BoolVar(&allowWrite, "allow-write", false, "Enable commands that change Jenkins state, such as build-job and abort-build")
+	globalFlags.StringVar(&url, "url", "", "Jenkins URL, overriding the JENKINS_URL env var and the config file")
+	globalFlags.StringVar(&user, "user", "", "Jenkins username, overriding the JENKINS_USER env var and the config file")
+	globalFlags.StringVar(&contextName, "context", "", "Configured Jenkins server to use instead of the current context (or JENKINS_CONTEXT env var)")
+}
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	flag.Usage = func() {
-		w := flag.CommandLine.Output()
-		fmt.Fprintf(w, "Usage:\n")
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, "  jenkins configure <url> [username] - Configure Jenkins URL and API token (reads token from stdin)")
-		fmt.Fprintln(w, "  jenkins list-jobs - List all Jenkins jobs")
-		fmt.Fprintln(w, "  jenkins get-job <job-name> - Get details of a specific job")
-		fmt.Fprintln(w, "  jenkins get-build <job-name> <build-number> - Get details of a specific build")
-		fmt.Fprintln(w, "  jenkins get-build-log <job-name> <build-number> - Get the console output of a build")
-		fmt.Fprintln(w, "  jenkins mcp-server - Start MCP server (Model Context Protocol)")
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, "Options:")
-		flag.PrintDefaults()
+	// The flag package has already printed the error and the usage
+	if err := globalFlags.Parse(os.Args[1:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitSuccess)
+	} else if err != nil {
+		os.Exit(exitUsage)
+	}
+
+	if contextName == "" {
+		contextName = os.Getenv("JENKINS_CONTEXT")
 	}
-	flag.Parse()
+	config.SelectContext(contextName)
 
-	if err := run(ctx, flag.Args()); err != nil {
+	if err := run(ctx, globalFlags.Args()); err != nil {
+		// The build's result has already been printed, so only the exit code is needed
+		var resultErr *buildResultError
+		if errors.As(err, &resultErr) {
+			os.Exit(resultErr.exitCode())
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		flag.Usage()
-		os.Exit(1)
+		kind := classifyError(err)
+		// A command used wrongly prints its own help, anything else used wrongly the usage
+		var usageErr *commandUsageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintln(os.Stderr)
+			usageErr.command.printHelp(os.Stderr)
+		} else if kind == kindUsage {
+			globalFlags.Usage()
+		}
+		os.Exit(kind.exitCode())
 	}
 }
 
 func run(ctx context.Context, args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: jenkins <command> [args...]")
+		return usageErrorf("usage: jenkins <command> [args...]")
 	}
 
-	// First argument is the command
-	command := args[0]
+	c, rest, err := lookupCommand(args)
+	if err != nil {
+		return err
+	}
+	return c.execute(ctx, rest)
+}
 
-	switch command {
-	case "configure":
-		if len(args) < 2 {
-			return fmt.Errorf("usage: jenkins configure <url> [username]")
-		}
-		username := ""
-		if len(args) >= 3 {
-			username = args[2]
+// parseCommandFlags parses a sub-command's flags, which may appear before, between or after its positional arguments,
+// and returns the positional arguments
+func parseCommandFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError(err)
 		}
-		return configure(args[1], username)
-	case "list-jobs":
-		return executeCommand(ctx, listJobs)
-	case "get-job":
-		if len(args) < 2 {
-			return fmt.Errorf("usage: jenkins get-job <job-name>")
+		remaining := fs.Args()
+		if len(remaining) == 0 {
+			return positional, nil
 		}
-		jobName := args[1]
-		return executeCommand(ctx, func(ctx context.Context) error {
-			return getJob(ctx, jobName)
-		})
-	case "get-build":
-		if len(args) < 3 {
-			return fmt.Errorf("usage: jenkins get-build <job-name> <build-number>")
+		// Everything after a "--" terminator is positional
+		if consumed := len(args) - len(remaining); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, remaining...), nil
 		}
-		jobName := args[1]
-		buildNumber := args[2]
-		return executeCommand(ctx, func(ctx context.Context) error {
-			return getBuild(ctx, jobName, buildNumber)
-		})
-	case "get-build-log":
-		if len(args) < 3 {
-			return fmt.Errorf("usage: jenkins get-build-log <job-name> <build-number>")
-		}
-		jobName := args[1]
-		buildNumber := args[2]
-		return executeCommand(ctx, func(ctx context.Context) error {
-			return getBuildLog(ctx, jobName, buildNumber)
-		})
-	case "mcp-server":
-		return runMCPServer(ctx)
-	default:
-		return fmt.Errorf("unknown sub-command: %s", command)
+		positional = append(positional, remaining[0])
+		args = remaining[1:]
 	}
 }
 
-func executeCommand(ctx context.Context, fn func(context.Context) error) error {
-	// Load URL and username from config file, or fall back to env var
-	if url == "" {
-		var err error
-		var configUsername string
-		url, configUsername, err = config.LoadConfig()
-		if err != nil {
-			// Fall back to environment variable
-			url = os.Getenv("JENKINS_URL")
-		} else if user == "" && configUsername != "" {
-			// Use username from config if not already set
-			user = configUsername
-		}
-	}
+// jobFilterFlags are the job filtering flags shared by list-jobs and search-jobs
+type jobFilterFlags struct {
+	depth           int
+	regex           bool
+	status          string
+	includeDisabled bool
+	building        bool
+}
 
-	// Load token from keyring, or fall back to env var
-	if token == "" {
-		token = os.Getenv("JENKINS_TOKEN")
-	}
-	if token == "" {
-		var err error
-		token, err = config.LoadToken(url)
-		if err != nil {
-			return fmt.Errorf("token not found, please run 'jenkins configure <url>' first")
-		}
-	}
+func (f *jobFilterFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.depth, "depth", 0, "List jobs inside folders down to this folder depth, top-level jobs being depth 1 (0 is unlimited when recursive)")
+	fs.BoolVar(&f.regex, "regex", false, "Match job paths with a regular expression rather than a glob")
+	fs.StringVar(&f.status, "status", "", "Only list jobs with these statuses, comma-separated (e.g. FAILURE,UNSTABLE)")
+	fs.BoolVar(&f.includeDisabled, "include-disabled", false, "Include disabled jobs")
+	fs.BoolVar(&f.building, "building", false, "Only list jobs with a build in progress")
+}
 
-	// Load user from environment variable if not set
-	if user == "" {
-		user = os.Getenv("JENKINS_USER")
-		if user == "" {
-			user = "admin" // Default username
-		}
-	}
+func (f *jobFilterFlags) options(recursive bool, pattern string) (jobListOptions, error) {
+	return newJobListOptions(recursive, f.depth, pattern, f.regex, f.status, f.includeDisabled, f.building)
+}
 
-	if url == "" {
-		return fmt.Errorf("Jenkins URL is required")
+// requireWriteAccess returns an error unless commands that change Jenkins state have been enabled,
+// either with --allow-write or in the context of the Jenkins in use
+func requireWriteAccess(command string) error {
+	if allowWrite {
+		return nil
 	}
-	if token == "" {
-		return fmt.Errorf("token is required")
+	creds, err := commandCredentials()
+	if err != nil {
+		return err
 	}
+	if creds.AllowWrite {
+		return nil
+	}
+	return fmt.Errorf("%s changes Jenkins state and requires write access: pass --allow-write, or run 'jenkins configure <url> [username] --allow-write'", command)
+}
 
-	// Create Jenkins client with the full URL
-	var err error
-	jenkins, err = gojenkins.CreateJenkins(nil, url, user, token).Init(ctx)
+func executeCommand(ctx context.Context, fn func(context.Context) error) error {
+	creds, err := commandCredentials()
 	if err != nil {
-		return fmt.Errorf("failed to create Jenkins client: %w", err)
+		return err
+	}
+	jenkins, err = connect(ctx, creds)
+	if err != nil {
+		return err
 	}
-
 	return fn(ctx)
 }
 
-// configure reads the token from stdin and saves it to the keyring
-func configure(jenkinsURL, username string) error {
-	if jenkinsURL == "" {
-		return fmt.Errorf("Jenkins URL is required")
+// resolveCredentials resolves the Jenkins URL, username, token and context settings from the flags, env vars,
+// config file, keyring and netrc file, in that order
+func resolveCredentials() (*config.Credentials, error) {
+	creds, err := config.Resolve(config.Overrides{URL: url, Username: user})
+	if errors.Is(err, config.ErrUnknownContext) {
+		// A context was asked for by name, so using another server instead would be a surprise
+		return nil, fmt.Errorf("%w (see 'jenkins list-contexts')", err)
 	}
+	return creds, err
+}
 
-	if username == "" {
-		username = "admin"
+// commandCredentials returns the credentials of the command being run, resolving them the first time
+func commandCredentials() (*config.Credentials, error) {
+	if credentials == nil {
+		creds, err := resolveCredentials()
+		if err != nil {
+			return nil, err
+		}
+		credentials = creds
 	}
+	return credentials, nil
+}
 
-	// Display the URL to the user
-	fmt.Fprintf(os.Stderr, "To create an API token in Jenkins:\n")
-	fmt.Fprintf(os.Stderr, "1. Go to: %s/user/%s/configure\n", jenkinsURL, username)
-	fmt.Fprintf(os.Stderr, "2. Click 'Add new Token' under API Token section\n")
-	fmt.Fprintf(os.Stderr, "3. Copy the generated token\n")
-	fmt.Fprintf(os.Stderr, "\nThe token will be stored securely in your system's keyring.\n")
-	fmt.Fprintf(os.Stderr, "\nEnter Jenkins API token: ")
+// connect creates a Jenkins client with resolved credentials
+func connect(ctx context.Context, creds *config.Credentials) (*gojenkins.Jenkins, error) {
+	if creds.URL == "" {
+		return nil, fmt.Errorf("Jenkins URL must be configured (use 'jenkins configure <url>', set JENKINS_URL env var or pass --url)")
+	}
+	if creds.Token == "" {
+		return nil, fmt.Errorf("Jenkins token must be set for %s (use 'jenkins configure <url>', set JENKINS_TOKEN env var or add it to ~/.netrc)", creds.URL)
+	}
 
-	// Read password with hidden input
-	tokenBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Fprintln(os.Stderr) // Print newline after hidden input
+	httpClient, err := newHTTPClient(creds.TLS, creds.HTTP)
 	if err != nil {
-		return fmt.Errorf("failed to read token: %w", err)
+		return nil, err
 	}
 
-	token := string(tokenBytes)
-	if token == "" {
-		return fmt.Errorf("token cannot be empty")
+	// Create Jenkins client with the full URL
+	client := gojenkins.CreateJenkins(httpClient, creds.URL, creds.Username, creds.Token)
+	if _, err := client.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create Jenkins client: %w", initError(ctx, client, err))
 	}
+	return client, nil
+}
 
-	// Save URL and username to config file
-	if err := config.SaveConfig(jenkinsURL, username); err != nil {
+// initError returns the error of a failed Init. Init does not report the status Jenkins responded with, so ask
+// again for it, to tell a rejected token from a wrong URL.
+func initError(ctx context.Context, client *gojenkins.Jenkins, err error) error {
+	if classifyError(err) != kindFailure {
 		return err
 	}
-
-	// Save token to keyring
-	if err := config.SaveToken(jenkinsURL, token); err != nil {
+	resp, probeErr := openStream(ctx, client, "/api/json", nil)
+	if probeErr != nil {
 		return err
 	}
-
-	fmt.Fprintf(os.Stderr, "Configuration saved successfully for URL: %s (username: %s, override with JENKINS_USER env var)\n", jenkinsURL, username)
-	return nil
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp)
+	}
+	return err
 }
 
 // listJobs lists all Jenkins jobs
-func listJobs(ctx context.Context) error {
-	jobs, err := jenkins.GetAllJobNames(ctx)
+func listJobs(ctx context.Context, opts jobListOptions, p printer) error {
+	jobs, err := fetchJobList(ctx, jenkins, opts)
 	if err != nil {
-		return fmt.Errorf("failed to list jobs: %w", err)
+		return err
 	}
+	return p(os.Stdout, jobs)
+}
 
-	// Filter out disabled jobs
-	enabledJobs := []gojenkins.InnerJob{}
-	for _, job := range jobs {
-		if !strings.HasPrefix(job.Color, "disabled") {
-			enabledJobs = append(enabledJobs, job)
-		}
+// getJob gets details of a specific job
+func getJob(ctx context.Context, jobName string, p printer) error {
+	job, err := fetchJob(ctx, jenkins, jobName)
+	if err != nil {
+		return err
 	}
+	return p(os.Stdout, job)
+}
 
-	if len(enabledJobs) == 0 {
-		fmt.Println("No jobs found")
-		return nil
+func getJenkinsBuild(ctx context.Context, jobName string, job *gojenkins.Job, id int64) (*gojenkins.Build, error) {
+	jobURL := jobPath(jobName) // hack for broken base URL
+	build := gojenkins.Build{Jenkins: job.Jenkins, Job: job, Raw: new(gojenkins.BuildResponse), Depth: 1, Base: jobURL + "/" + strconv.FormatInt(id, 10)}
+	status, err := build.Poll(ctx)
+	if err != nil {
+		return nil, err
 	}
+	if status == 200 {
+		return &build, nil
+	}
+	return nil, &statusError{Code: status, Status: strconv.Itoa(status)}
+}
 
-	fmt.Printf("Found %d job(s):\n\n", len(enabledJobs))
-	for _, job := range enabledJobs {
-		status := getStatusFromColor(job.Color)
-		fmt.Printf("%-40s %-15s %s\n", job.Name, status, job.Url)
+// listBuilds lists the builds of a job
+func listBuilds(ctx context.Context, jobName string, filter buildFilter, p printer) error {
+	builds, err := fetchBuildList(ctx, jenkins, jobName, filter)
+	if err != nil {
+		return err
 	}
+	return p(os.Stdout, builds)
+}
 
+// getBuild gets details of a specific build, optionally reporting its result as a buildResultError
+func getBuild(ctx context.Context, jobName, buildNumber string, exitStatus bool, p printer) error {
+	build, err := fetchBuild(ctx, jenkins, jobName, buildNumber)
+	if err != nil {
+		return err
+	}
+	if err := p(os.Stdout, build); err != nil {
+		return err
+	}
+	if exitStatus {
+		return checkBuildResult(build)
+	}
 	return nil
 }
 
-// getJob gets details of a specific job
-func getJob(ctx context.Context, jobName string) error {
-	job, err := jenkins.GetJob(ctx, jobName)
+// waitBuild waits for a build to finish, then prints its details and reports its result as a buildResultError
+func waitBuild(ctx context.Context, jobName, buildNumber string, interval, timeout time.Duration, p printer) error {
+	build, err := lookupBuild(ctx, jenkins, jobName, buildNumber)
 	if err != nil {
-		return fmt.Errorf("failed to get job: %w", err)
+		return err
 	}
 
-	printField("Job Name", job.GetName())
-	printField("URL", job.Raw.URL)
-	// Only show status if it's not empty
-	status := getStatusFromColor(job.Raw.Color)
-	if status != "" {
-		printField("Status", status)
-	}
-	if job.GetDescription() != "" {
-		printField("Description", job.GetDescription())
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	lastBuild, err := job.GetLastBuild(ctx)
-	if err == nil && lastBuild != nil {
-		printField("Last Build", fmt.Sprintf("#%d - %s (%s)", lastBuild.GetBuildNumber(), lastBuild.GetResult(), lastBuild.GetUrl()))
+	detail, err := waitForBuild(waitCtx, build, interval)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		detail = newBuildDetail(build)
+	} else if err != nil {
+		return err
 	}
-
-	lastSuccess, err := job.GetLastSuccessfulBuild(ctx)
-	if err == nil && lastSuccess != nil {
-		printField("Last Success", fmt.Sprintf("#%d (%s)", lastSuccess.GetBuildNumber(), lastSuccess.GetUrl()))
+	if err := p(os.Stdout, detail); err != nil {
+		return err
 	}
+	return checkBuildResult(detail)
+}
 
-	lastFailed, err := job.GetLastFailedBuild(ctx)
-	if err == nil && lastFailed != nil {
-		printField("Last Failed", fmt.Sprintf("#%d (%s)", lastFailed.GetBuildNumber(), lastFailed.GetUrl()))
+// getBuildLog gets the console output of a build, keeping only the parts selected by the filter
+func getBuildLog(ctx context.Context, jobName, buildNumber string, filter logFilter, p printer) error {
+	log, err := fetchBuildLog(ctx, jenkins, jobName, buildNumber, filter)
+	if err != nil {
+		return err
 	}
+	return p(os.Stdout, log)
+}
 
-	// Print inner jobs if they exist (for folders and multi-branch pipelines)
-	innerJobs := job.GetInnerJobsMetadata()
-	// Filter out disabled inner jobs
-	enabledInnerJobs := []gojenkins.InnerJob{}
-	for _, innerJob := range innerJobs {
-		if !strings.HasPrefix(innerJob.Color, "disabled") {
-			enabledInnerJobs = append(enabledInnerJobs, innerJob)
+// getStages gets the stages of a pipeline build, or the log of one stage if stageName is set
+func getStages(ctx context.Context, jobName, buildNumber, stageName string, p printer) error {
+	if stageName != "" {
+		log, err := fetchStageLog(ctx, jenkins, jobName, buildNumber, stageName)
+		if err != nil {
+			return err
 		}
+		return p(os.Stdout, log)
 	}
-	if len(enabledInnerJobs) > 0 {
-		fmt.Printf("\nInner Jobs (%d):\n", len(enabledInnerJobs))
-		for _, innerJob := range enabledInnerJobs {
-			status := getStatusFromColor(innerJob.Color)
-			fmt.Printf("  %-38s %-15s %s\n", innerJob.Name, status, innerJob.Url)
-		}
+	stages, err := fetchStages(ctx, jenkins, jobName, buildNumber)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return p(os.Stdout, stages)
 }
 
-func getJenkinsBuild(ctx context.Context, jobName string, job *gojenkins.Job, id int64) (*gojenkins.Build, error) {
-	jobURL := "/job/" + jobName // hack for broken base URL
-	build := gojenkins.Build{Jenkins: job.Jenkins, Job: job, Raw: new(gojenkins.BuildResponse), Depth: 1, Base: jobURL + "/" + strconv.FormatInt(id, 10)}
-	status, err := build.Poll(ctx)
+// getTestReport gets the JUnit test report of a build
+func getTestReport(ctx context.Context, jobName, buildNumber, suite string, p printer) error {
+	report, err := fetchTestReport(ctx, jenkins, jobName, buildNumber, suite)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if status == 200 {
-		return &build, nil
+	return p(os.Stdout, report)
+}
+
+// listArtifacts lists the artifacts of a build
+func listArtifacts(ctx context.Context, jobName, buildNumber, glob string, p printer) error {
+	artifacts, err := fetchArtifacts(ctx, jenkins, jobName, buildNumber, glob)
+	if err != nil {
+		return err
 	}
-	return nil, errors.New(strconv.Itoa(status))
+	return p(os.Stdout, artifacts)
 }
 
-// getBuild gets details of a specific build
-func getBuild(ctx context.Context, jobName, buildNumber string) error {
-	job, err := jenkins.GetJob(ctx, jobName)
+// downloadBuildArtifacts downloads the artifacts of a build, showing progress when stderr is a terminal
+func downloadBuildArtifacts(ctx context.Context, jobName, buildNumber, glob, dest string, concurrency int, p printer) error {
+	progress := term.IsTerminal(int(os.Stderr.Fd()))
+	result, err := downloadArtifacts(ctx, jenkins, jobName, buildNumber, glob, dest, concurrency, progress)
 	if err != nil {
-		return fmt.Errorf("failed to get job: %w", err)
+		return err
 	}
-	buildNum, err := parseBuildNumber(buildNumber)
+	return p(os.Stdout, result)
+}
+
+// listQueue lists the items waiting in the build queue
+func listQueue(ctx context.Context, p printer) error {
+	queue, err := fetchQueue(ctx, jenkins)
 	if err != nil {
 		return err
 	}
+	return p(os.Stdout, queue)
+}
 
-	build, err := getJenkinsBuild(ctx, jobName, job, buildNum)
+// getQueueItem gets a queue item
+func getQueueItem(ctx context.Context, id int64, p printer) error {
+	item, err := fetchQueueItem(ctx, jenkins, id)
 	if err != nil {
-		return fmt.Errorf("failed to get build: %w", err)
+		return err
 	}
+	return p(os.Stdout, item)
+}
 
-	printBuildDetails(ctx, build)
+// waitQueueItem waits for a queue item to start a build, then prints it. It returns an error if the timeout
+// is reached first.
+func waitQueueItem(ctx context.Context, id int64, interval, timeout time.Duration, p printer) error {
+	item, err := awaitQueueItem(ctx, jenkins, id, interval, timeout)
+	if err != nil {
+		return err
+	}
+	if err := p(os.Stdout, item); err != nil {
+		return err
+	}
+	if item.Build == nil {
+		return fmt.Errorf("queue item %d did not start a build within %s", id, timeout)
+	}
 	return nil
 }
 
-// getBuildLog gets the console output of a build
-func getBuildLog(ctx context.Context, jobName, buildNumber string) error {
-	job, err := jenkins.GetJob(ctx, jobName)
+// buildJob triggers a build and waits for it to leave the queue. It then prints the build, or with wait
+// prints it once finished, or with follow streams its log; both report the result as a buildResultError.
+func buildJob(ctx context.Context, jobName string, params buildParams, wait bool, interval time.Duration, follow bool, p printer) error {
+	queueID, err := triggerBuild(ctx, jenkins, jobName, params)
 	if err != nil {
-		return fmt.Errorf("failed to get job: %w", err)
+		return err
 	}
+	fmt.Fprintf(os.Stderr, "Queued %s as queue item #%d\n", jobName, queueID)
 
-	buildNum, err := parseBuildNumber(buildNumber)
+	item, err := waitForQueueItem(ctx, jenkins, queueID, queueInterval)
 	if err != nil {
 		return err
 	}
+	number := item.Build.Number
+	buildNumber := strconv.FormatInt(number, 10)
+	if follow {
+		fmt.Fprintf(os.Stderr, "Started build #%d\n", number)
+		return followBuildLog(ctx, jenkins, jobName, buildNumber, os.Stdout)
+	}
 
-	build, err := getJenkinsBuild(ctx, jobName, job, buildNum)
+	build, err := lookupBuild(ctx, jenkins, jobName, buildNumber)
 	if err != nil {
-		return fmt.Errorf("failed to get build: %w", err)
+		return err
+	}
+	if !wait {
+		return p(os.Stdout, newBuildDetail(build))
+	}
+	fmt.Fprintf(os.Stderr, "Started build #%d, waiting for it to finish\n", number)
+	detail, err := waitForBuild(ctx, build, interval)
+	if err != nil {
+		return err
 	}
+	if err := p(os.Stdout, detail); err != nil {
+		return err
+	}
+	return checkBuildResult(detail)
+}
 
-	log := build.GetConsoleOutput(ctx)
-	fmt.Print(log)
-	return nil
+// abortRunningBuild aborts a running build, asking for confirmation on the terminal unless yes is set,
+// then prints the build
+func abortRunningBuild(ctx context.Context, jobName, buildNumber string, yes, escalate bool, grace time.Duration, p printer) error {
+	confirm := func(build *buildDetail) (bool, error) {
+		if yes {
+			return true, nil
+		}
+		return confirmPrompt(fmt.Sprintf("Abort build #%d of %s (%s)?", build.Number, jobName, build.URL))
+	}
+	build, err := abortBuild(ctx, jenkins, jobName, buildNumber, escalate, grace, confirm)
+	if err != nil {
+		return err
+	}
+	return p(os.Stdout, build)
 }
 
 // Helper functions
 
+// confirmPrompt asks a yes/no question on the terminal, defaulting to no. It fails if stdin is not a terminal,
+// so that scripts must confirm explicitly (e.g. with --yes).
+func confirmPrompt(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("confirmation required but stdin is not a terminal (pass --yes to skip it)")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
 func parseBuildNumber(buildNumber string) (int64, error) {
 	var num int64
 	_, err := fmt.Sscanf(buildNumber, "%d", &num)
@@ -348,42 +478,26 @@ func parseBuildNumber(buildNumber string) (int64, error) {
 	return num, nil
 }
 
-func printBuildDetails(ctx context.Context, build *gojenkins.Build) {
-	printField("Build Number", build.GetBuildNumber())
-	printField("URL", build.GetUrl())
-	status := build.GetResult()
-	if build.IsRunning(ctx) {
-		status = "BUILDING"
-	}
-	printField("Status", status)
-	if build.Raw.Description != "" {
-		printField("Description", build.Raw.Description)
-	}
-	buildTime := build.GetTimestamp()
-	if !buildTime.IsZero() {
-		printField("Started", humanize.Time(buildTime))
-	}
-	duration := build.GetDuration()
-	if duration > 0 {
-		printField("Duration", formatDuration(duration))
-	}
-}
-
 // printField prints a field with proper formatting
-func printField(key string, value interface{}) {
+func printField(w io.Writer, key string, value interface{}) {
 	valueStr := fmt.Sprint(value)
 	multiLine := strings.Contains(valueStr, "\n")
-	fmt.Printf("%-20s", key+":")
+	fmt.Fprintf(w, "%-20s", key+":")
 	if !multiLine {
-		fmt.Printf(" %s\n", valueStr)
+		fmt.Fprintf(w, " %s\n", valueStr)
 	} else {
-		fmt.Println()
+		fmt.Fprintln(w)
 		for _, line := range strings.Split(valueStr, "\n") {
-			fmt.Printf("%-20s %s\n", "", line)
+			fmt.Fprintf(w, "%-20s %s\n", "", line)
 		}
 	}
 }
 
+// isBuildingColor reports whether a Jenkins color shows a build in progress
+func isBuildingColor(color string) bool {
+	return strings.HasSuffix(color, "_anime")
+}
+
 // getStatusFromColor converts Jenkins color to status
 func getStatusFromColor(color string) string {
 	// Return empty string for empty color (non-buildable jobs like folders, or jobs never built)
@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/bndr/gojenkins"
 )
 
 // TestGetStatusFromColor tests the color to status conversion
@@ -38,8 +43,11 @@ func TestGetStatusFromColor(t *testing.T) {
 	}
 }
 
-// TestRun_BuildJobRejected verifies that the build-job command is properly rejected
+// TestRun_BuildJobRejected verifies that the build-job command is rejected unless write access is enabled
 func TestRun_BuildJobRejected(t *testing.T) {
+	// Use an empty config directory so write access is not enabled by the user's config
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
 	ctx := context.Background()
 	err := run(ctx, []string{"build-job", "test-job"})
 
@@ -47,7 +55,76 @@ func TestRun_BuildJobRejected(t *testing.T) {
 		t.Fatal("Expected error for build-job command, got nil")
 	}
 
-	if !strings.Contains(err.Error(), "unknown sub-command: build-job") {
-		t.Errorf("Expected 'unknown sub-command: build-job' error, got: %v", err)
+	if !strings.Contains(err.Error(), "requires write access") {
+		t.Errorf("Expected 'requires write access' error, got: %v", err)
+	}
+}
+
+// TestRun_BuildJobWaitAndFollow verifies that --wait and --follow cannot be combined
+func TestRun_BuildJobWaitAndFollow(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	allowWrite = true
+	defer func() { allowWrite = false }()
+
+	err := run(context.Background(), []string{"build-job", "test-job", "--wait", "--follow"})
+	if classifyError(err) != kindUsage || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("Expected a usage error, got: %v", err)
+	}
+}
+
+// newTestJenkins starts a fake Jenkins server serving the given handlers and returns a client connected to it.
+// The root API endpoint used to initialize the client is served automatically unless given.
+func newTestJenkins(t *testing.T, handlers map[string]http.HandlerFunc) *gojenkins.Jenkins {
+	t.Helper()
+	mux := http.NewServeMux()
+	if _, ok := handlers["/api/json/"]; !ok {
+		mux.HandleFunc("/api/json/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"jobs":[]}`))
+		})
+	}
+	for pattern, handler := range handlers {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := gojenkins.CreateJenkins(server.Client(), server.URL, "admin", "test-token").Init(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to create Jenkins client: %v", err)
+	}
+	return client
+}
+
+// TestParseCommandFlags tests that sub-command flags may be interspersed with positional arguments
+func TestParseCommandFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		follow     bool
+	}{
+		{[]string{"app", "42"}, []string{"app", "42"}, false},
+		{[]string{"--follow", "app", "42"}, []string{"app", "42"}, true},
+		{[]string{"app", "42", "--follow"}, []string{"app", "42"}, true},
+		{[]string{"app", "-f", "42"}, []string{"app", "42"}, true},
+		{[]string{"--", "-app", "42"}, []string{"-app", "42"}, false},
+		{[]string{"app", "--", "--follow"}, []string{"app", "--follow"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			follow := fs.Bool("follow", false, "")
+			fs.BoolVar(follow, "f", false, "")
+			positional, err := parseCommandFlags(fs, tt.args)
+			if err != nil {
+				t.Fatalf("parseCommandFlags returned error: %v", err)
+			}
+			if strings.Join(positional, " ") != strings.Join(tt.positional, " ") {
+				t.Errorf("positional = %q, want %q", positional, tt.positional)
+			}
+			if *follow != tt.follow {
+				t.Errorf("follow = %v, want %v", *follow, tt.follow)
+			}
+		})
 	}
 }
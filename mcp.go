@@ -2,46 +2,22 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/bndr/gojenkins"
-	"github.com/kitproj/jenkins-cli/internal/config"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 // runMCPServer starts the MCP server that communicates over stdio using the mcp-go library
 func runMCPServer(ctx context.Context) error {
-	// Load URL and username from config file
-	url, username, err := config.LoadConfig()
+	// Resolve credentials exactly as the CLI does, so the MCP server works wherever the CLI does
+	creds, err := resolveCredentials()
 	if err != nil {
-		return fmt.Errorf("Jenkins URL must be configured (use 'jenkins configure <url>' or set JENKINS_URL env var)")
+		return err
 	}
-
-	// Load token from keyring
-	token, err := config.LoadToken(url)
-	if err != nil {
-		return fmt.Errorf("Jenkins token must be set (use 'jenkins configure <url>' or set JENKINS_TOKEN env var)")
-	}
-
-	if url == "" {
-		return fmt.Errorf("Jenkins URL must be configured (use 'jenkins configure <url>')")
-	}
-	if token == "" {
-		return fmt.Errorf("Jenkins token must be set (use 'jenkins configure <url>')")
-	}
-
-	// Use username from config, or default to "admin"
-	if username == "" {
-		username = "admin"
-	}
-
-	// Create Jenkins client with the full URL
-	jenkinsClient, err := gojenkins.CreateJenkins(nil, url, username, token).Init(ctx)
+	jenkinsClient, err := connect(ctx, creds)
 	if err != nil {
-		return fmt.Errorf("failed to create Jenkins client: %w", err)
+		return err
 	}
 
 	// Create a new MCP server
@@ -51,230 +27,230 @@ func runMCPServer(ctx context.Context) error {
 		server.WithToolCapabilities(true),
 	)
 
-	// Add list-jobs tool
-	listJobsTool := mcp.NewTool("list_jobs",
-		mcp.WithDescription("List all Jenkins jobs with their status and URL"),
-	)
-	s.AddTool(listJobsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return listJobsHandler(ctx, jenkinsClient, request)
-	})
-
-	// Add get-job tool
-	getJobTool := mcp.NewTool("get_job",
-		mcp.WithDescription("Get details of a specific Jenkins job including status, description, and build history"),
-		mcp.WithString("job_name",
-			mcp.Required(),
-			mcp.Description("Jenkins job name"),
-		),
-	)
-	s.AddTool(getJobTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return getJobHandler(ctx, jenkinsClient, request)
-	})
-
-	// Add get-build tool
-	getBuildTool := mcp.NewTool("get_build",
-		mcp.WithDescription("Get details of a specific build including status, duration, and timestamp"),
-		mcp.WithString("job_name",
-			mcp.Required(),
-			mcp.Description("Jenkins job name"),
-		),
-		mcp.WithString("build_number",
-			mcp.Required(),
-			mcp.Description("Build number (e.g., '42')"),
-		),
-	)
-	s.AddTool(getBuildTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return getBuildHandler(ctx, jenkinsClient, request)
-	})
-
-	// Add get-build-log tool
-	getBuildLogTool := mcp.NewTool("get_build_log",
-		mcp.WithDescription("Get the console output of a specific build"),
-		mcp.WithString("job_name",
-			mcp.Required(),
-			mcp.Description("Jenkins job name"),
-		),
-		mcp.WithString("build_number",
-			mcp.Required(),
-			mcp.Description("Build number (e.g., '42')"),
-		),
-	)
-	s.AddTool(getBuildLogTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return getBuildLogHandler(ctx, jenkinsClient, request)
-	})
+	// Add the tools of the commands that have one
+	for _, c := range commands {
+		if c.tool == nil {
+			continue
+		}
+		s.AddTool(c.newTool(), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return c.callTool(ctx, jenkinsClient, request)
+		})
+	}
 
 	// Start the stdio server
 	return server.ServeStdio(s)
 }
 
-func listJobsHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	jobs, err := client.GetAllJobNames(ctx)
+func listJobsHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	opts, err := newJobListOptions(
+		flags.getBool("recursive"),
+		flags.getInt("depth"),
+		flags.getString("name"),
+		flags.getBool("regex"),
+		flags.getString("status"),
+		flags.getBool("include-disabled"),
+		flags.getBool("building"),
+	)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to list jobs: %v", err)), nil
+		return toolError(usageError(err)), nil
 	}
 
-	// Filter out disabled jobs
-	enabledJobs := []gojenkins.InnerJob{}
-	for _, job := range jobs {
-		if !strings.HasPrefix(job.Color, "disabled") {
-			enabledJobs = append(enabledJobs, job)
-		}
+	jobs, err := fetchJobList(ctx, client, opts)
+	if err != nil {
+		return toolError(err), nil
 	}
+	return toolResult(jobs), nil
+}
 
-	if len(enabledJobs) == 0 {
-		return mcp.NewToolResultText("No jobs found"), nil
+func getJobHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	jobName, err := request.RequireString("job_name")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	result := fmt.Sprintf("Found %d job(s):\n\n", len(enabledJobs))
-	for _, job := range enabledJobs {
-		status := getStatusFromColor(job.Color)
-		result += fmt.Sprintf("%-40s %-15s %s\n", job.Name, status, job.Url)
+	job, err := fetchJob(ctx, client, jobName)
+	if err != nil {
+		return toolError(err), nil
 	}
-
-	return mcp.NewToolResultText(result), nil
+	return toolResult(job), nil
 }
 
-func getJobHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func listBuildsHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
 	jobName, err := request.RequireString("job_name")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid 'job_name' argument: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	job, err := client.GetJob(ctx, jobName)
+	filter, err := newBuildFilter(
+		flags.getInt("limit"),
+		flags.getString("result"),
+		flags.getString("since"),
+		flags.getString("branch"),
+		flags.getString("started-by"),
+	)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get job: %v", err)), nil
+		return toolError(usageError(err)), nil
 	}
 
-	result := fmt.Sprintf("Job Name: %s\nURL: %s",
-		job.GetName(),
-		job.Raw.URL,
-	)
-
-	// Only show status if it's not empty
-	status := getStatusFromColor(job.Raw.Color)
-	if status != "" {
-		result += fmt.Sprintf("\nStatus: %s", status)
+	builds, err := fetchBuildList(ctx, client, jobName, filter)
+	if err != nil {
+		return toolError(err), nil
 	}
+	return toolResult(builds), nil
+}
 
-	if job.GetDescription() != "" {
-		result += fmt.Sprintf("\nDescription: %s", job.GetDescription())
+func getBuildHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	jobName, err := request.RequireString("job_name")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	lastBuild, err := job.GetLastBuild(ctx)
-	if err == nil && lastBuild != nil {
-		result += fmt.Sprintf("\nLast Build: #%d - %s (%s)", lastBuild.GetBuildNumber(), lastBuild.GetResult(), lastBuild.GetUrl())
+	buildNumber, err := request.RequireString("build_number")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'build_number' argument: %v", err)), nil
 	}
 
-	lastSuccess, err := job.GetLastSuccessfulBuild(ctx)
-	if err == nil && lastSuccess != nil {
-		result += fmt.Sprintf("\nLast Success: #%d (%s)", lastSuccess.GetBuildNumber(), lastSuccess.GetUrl())
+	build, err := fetchBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return toolError(err), nil
 	}
+	return toolResult(build), nil
+}
 
-	lastFailed, err := job.GetLastFailedBuild(ctx)
-	if err == nil && lastFailed != nil {
-		result += fmt.Sprintf("\nLast Failed: #%d (%s)", lastFailed.GetBuildNumber(), lastFailed.GetUrl())
+func getBuildLogHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	jobName, err := request.RequireString("job_name")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	// Add inner jobs if they exist (for folders and multi-branch pipelines)
-	innerJobs := job.GetInnerJobsMetadata()
-	// Filter out disabled inner jobs
-	enabledInnerJobs := []gojenkins.InnerJob{}
-	for _, innerJob := range innerJobs {
-		if !strings.HasPrefix(innerJob.Color, "disabled") {
-			enabledInnerJobs = append(enabledInnerJobs, innerJob)
-		}
+	buildNumber, err := request.RequireString("build_number")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'build_number' argument: %v", err)), nil
 	}
-	if len(enabledInnerJobs) > 0 {
-		result += fmt.Sprintf("\n\nInner Jobs (%d):", len(enabledInnerJobs))
-		for _, innerJob := range enabledInnerJobs {
-			status := getStatusFromColor(innerJob.Color)
-			result += fmt.Sprintf("\n  %-38s %-15s %s", innerJob.Name, status, innerJob.Url)
-		}
+
+	filter, err := newLogFilter(flags.getInt("head"), flags.getInt("tail"), flags.getString("grep"), flags.getBool("errors"), flags.getInt("context-lines"))
+	if err != nil {
+		return toolError(usageError(err)), nil
 	}
 
-	return mcp.NewToolResultText(result), nil
+	offset, limit := int64(request.GetInt("offset", 0)), int64(request.GetInt("limit_bytes", defaultLogLimit))
+	search := filter.Grep != nil || filter.Errors || filter.Head > 0
+	if filter.Tail > 0 && offset > 0 && !search {
+		return toolError(usageErrorf("offset cannot be used with tail alone, which reads the end of the log")), nil
+	}
+	var page *logPage
+	if search {
+		// Search the rest of the log rather than one page of it
+		page, err = fetchFilteredLogPage(ctx, client, jobName, buildNumber, offset, limit, filter)
+	} else if page, err = fetchLogPage(ctx, client, jobName, buildNumber, offset, limit, filter.Tail > 0); err == nil {
+		page.Log = filter.apply(page.Log)
+	}
+	if err != nil {
+		return toolError(err), nil
+	}
+	// The log is returned as text only, with the page's offsets as structured content, as duplicating the log
+	// as structured content would double its size
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.NewTextContent(textOf(page))},
+		StructuredContent: page,
+	}, nil
 }
 
-func getBuildHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func getBuildStagesHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
 	jobName, err := request.RequireString("job_name")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid 'job_name' argument: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	buildNumberStr, err := request.RequireString("build_number")
+	buildNumber, err := request.RequireString("build_number")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid 'build_number' argument: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'build_number' argument: %v", err)), nil
 	}
 
-	buildNumber, err := strconv.ParseInt(buildNumberStr, 10, 64)
-	if err != nil || buildNumber <= 0 {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid build number: %s", buildNumberStr)), nil
+	if stageName := flags.getString("stage"); stageName != "" {
+		log, err := fetchStageLog(ctx, client, jobName, buildNumber, stageName)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return toolResult(log), nil
 	}
-
-	job, err := client.GetJob(ctx, jobName)
+	stages, err := fetchStages(ctx, client, jobName, buildNumber)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get job: %v", err)), nil
+		return toolError(err), nil
 	}
+	return toolResult(stages), nil
+}
 
-	build, err := job.GetBuild(ctx, buildNumber)
+func getTestReportHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	jobName, err := request.RequireString("job_name")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get build: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'job_name' argument: %v", err)), nil
 	}
 
-	status := build.GetResult()
-	if build.IsRunning(ctx) {
-		status = "BUILDING"
-	}
-
-	result := fmt.Sprintf("Build Number: %d\nURL: %s\nStatus: %s",
-		build.GetBuildNumber(),
-		build.GetUrl(),
-		status,
-	)
-
-	if build.Raw.Description != "" {
-		result += fmt.Sprintf("\nDescription: %s", build.Raw.Description)
+	buildNumber, err := request.RequireString("build_number")
+	if err != nil {
+		return toolError(usageErrorf("Missing or invalid 'build_number' argument: %v", err)), nil
 	}
 
-	buildTime := build.GetTimestamp()
-	if !buildTime.IsZero() {
-		result += fmt.Sprintf("\nStarted: %s", buildTime.Format("2006-01-02 15:04:05"))
+	report, err := fetchTestReport(ctx, client, jobName, buildNumber, flags.getString("suite"))
+	if err != nil {
+		return toolError(err), nil
 	}
+	return toolResult(report), nil
+}
 
-	duration := build.GetDuration()
-	if duration > 0 {
-		result += fmt.Sprintf("\nDuration: %s", formatDuration(duration))
+func listQueueHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	queue, err := fetchQueue(ctx, client)
+	if err != nil {
+		return toolError(err), nil
 	}
-
-	return mcp.NewToolResultText(result), nil
+	return toolResult(queue), nil
 }
 
-func getBuildLogHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	jobName, err := request.RequireString("job_name")
+func getQueueItemHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	idStr, err := request.RequireString("id")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid 'job_name' argument: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'id' argument: %v", err)), nil
 	}
-
-	buildNumberStr, err := request.RequireString("build_number")
+	id, err := parseQueueID(idStr)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid 'build_number' argument: %v", err)), nil
+		return toolError(usageError(err)), nil
 	}
 
-	buildNumber, err := strconv.ParseInt(buildNumberStr, 10, 64)
-	if err != nil || buildNumber <= 0 {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid build number: %s", buildNumberStr)), nil
+	item, err := fetchQueueItem(ctx, client, id)
+	if err != nil {
+		return toolError(err), nil
 	}
+	return toolResult(item), nil
+}
 
-	job, err := client.GetJob(ctx, jobName)
+func waitQueueItemHandler(ctx context.Context, client *gojenkins.Jenkins, request mcp.CallToolRequest, flags toolFlags) (*mcp.CallToolResult, error) {
+	idStr, err := request.RequireString("id")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get job: %v", err)), nil
+		return toolError(usageErrorf("Missing or invalid 'id' argument: %v", err)), nil
 	}
-
-	build, err := job.GetBuild(ctx, buildNumber)
+	id, err := parseQueueID(idStr)
+	if err != nil {
+		return toolError(usageError(err)), nil
+	}
+	item, err := awaitQueueItem(ctx, client, id, flags.getDuration("interval"), flags.getDuration("timeout"))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get build: %v", err)), nil
+		return toolError(err), nil
 	}
+	return toolResult(item), nil
+}
+
+// toolResult returns a result as structured content, with its text form as the fallback for clients without structured content support
+func toolResult(r result) *mcp.CallToolResult {
+	return mcp.NewToolResultStructured(r, textOf(r))
+}
 
-	log := build.GetConsoleOutput(ctx)
-	return mcp.NewToolResultText(log), nil
+// toolError returns an error as a tool error result, with its kind as structured content so that agents can tell
+// e.g. a job that does not exist from Jenkins being unavailable
+func toolError(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.NewTextContent(err.Error())},
+		StructuredContent: newErrorPayload(err),
+		IsError:           true,
+	}
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printer renders a result in the format selected with --output
+type printer func(w io.Writer, r result) error
+
+// junitResult is a result that can be written as a JUnit XML report
+type junitResult interface {
+	writeJUnit(w io.Writer) error
+}
+
+// newPrinter returns the printer for an --output format: text, json, yaml, junit, go-template=TEMPLATE,
+// go-template-file=FILE, jsonpath=TEMPLATE or jsonpath-file=FILE
+func newPrinter(format string) (printer, error) {
+	name, arg, hasArg := strings.Cut(format, "=")
+	switch name {
+	case "", "text":
+		return printText, nil
+	case "json":
+		return printJSON, nil
+	case "yaml":
+		return printYAML, nil
+	case "junit":
+		return printJUnit, nil
+	case "go-template", "go-template-file", "jsonpath", "jsonpath-file":
+		if !hasArg || arg == "" {
+			return nil, usageErrorf("output format %s requires a template, e.g. --output %s=...", name, name)
+		}
+		if strings.HasSuffix(name, "-file") {
+			data, err := os.ReadFile(arg)
+			if err != nil {
+				return nil, usageError(fmt.Errorf("failed to read template file: %w", err))
+			}
+			arg = string(data)
+		}
+		if strings.HasPrefix(name, "go-template") {
+			return newGoTemplatePrinter(arg)
+		}
+		return newJSONPathPrinter(arg)
+	default:
+		return nil, usageErrorf("unknown output format: %s (must be one of text, json, yaml, junit, go-template, go-template-file, jsonpath, jsonpath-file)", format)
+	}
+}
+
+func printText(w io.Writer, r result) error {
+	r.writeText(w)
+	return nil
+}
+
+func printJSON(w io.Writer, r result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// printYAML renders the JSON form of the result as YAML, so both formats share the same field names and order
+func printYAML(w io.Writer, r result) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	clearStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// printJUnit renders a test report as JUnit XML
+func printJUnit(w io.Writer, r result) error {
+	report, ok := r.(junitResult)
+	if !ok {
+		return fmt.Errorf("output format junit is only supported by get-test-report")
+	}
+	return report.writeJUnit(w)
+}
+
+// newGoTemplatePrinter returns a printer that executes a Go template against the result, e.g. "{{.Number}} {{.Result}}"
+func newGoTemplatePrinter(text string) (printer, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, usageErrorf("invalid go-template: %w", err)
+	}
+	return func(w io.Writer, r result) error {
+		if err := tmpl.Execute(w, r); err != nil {
+			return fmt.Errorf("failed to execute go-template: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// newJSONPathPrinter returns a printer that evaluates a JSONPath template against the JSON form of the result
+func newJSONPathPrinter(text string) (printer, error) {
+	path, err := parseJSONPath(text)
+	if err != nil {
+		return nil, usageErrorf("invalid jsonpath: %w", err)
+	}
+	return func(w io.Writer, r result) error {
+		if err := path.execute(w, r); err != nil {
+			return fmt.Errorf("failed to execute jsonpath: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// clearStyle resets the flow and quoting styles picked up from the JSON source to the YAML defaults
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// textOf returns the human-readable form of a result
+func textOf(r result) string {
+	var buf bytes.Buffer
+	r.writeText(&buf)
+	return strings.TrimRight(buf.String(), "\n")
+}
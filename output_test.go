@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewPrinter tests that each output format renders the same result
+func TestNewPrinter(t *testing.T) {
+	build := &buildDetail{
+		Number:    42,
+		URL:       "https://jenkins.example.com/job/app/42/",
+		Result:    "SUCCESS",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:  135000,
+	}
+
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{"text", []string{"Build Number:        42\n", "Status:              SUCCESS\n", "Duration:            2 minutes\n"}},
+		{"json", []string{`"number": 42`, `"result": "SUCCESS"`, `"building": false`, `"timestamp": "2024-01-02T03:04:05Z"`}},
+		{"yaml", []string{"number: 42\n", "result: SUCCESS\n", "building: false\n", "timestamp: \"2024-01-02T03:04:05Z\"\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := newPrinter(tt.format)
+			if err != nil {
+				t.Fatalf("newPrinter(%q) returned error: %v", tt.format, err)
+			}
+			var buf bytes.Buffer
+			if err := p(&buf, build); err != nil {
+				t.Fatalf("printer returned error: %v", err)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output %q does not contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+// TestNewPrinter_Unknown tests that an unknown output format is rejected
+func TestNewPrinter_Unknown(t *testing.T) {
+	if _, err := newPrinter("xml"); classifyError(err) != kindUsage {
+		t.Errorf("newPrinter(xml) = %v, want a usage error", err)
+	}
+}
+
+// TestJobListText tests the text rendering of job listings
+func TestJobListText(t *testing.T) {
+	if got := textOf(&jobList{}); got != "No jobs found" {
+		t.Errorf("textOf(empty job list) = %q, want %q", got, "No jobs found")
+	}
+
+	jobs := &jobList{Jobs: []jobSummary{{Name: "app", Status: "SUCCESS", URL: "https://jenkins.example.com/job/app/"}}}
+	got := textOf(jobs)
+	if !strings.HasPrefix(got, "Found 1 job(s):\n\n") || !strings.Contains(got, "app ") || !strings.Contains(got, "SUCCESS") {
+		t.Errorf("unexpected job list text: %q", got)
+	}
+}
+
+// TestNewPrinter_Templates tests the go-template and jsonpath output formats
+func TestNewPrinter_Templates(t *testing.T) {
+	build := &buildDetail{Number: 42, Result: "FAILURE"}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"go-template={{.Number}} {{.Result}}", "42 FAILURE"},
+		{"go-template={{.Status}}", "FAILURE"},
+		{"jsonpath={.number} {.result}", "42 FAILURE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := newPrinter(tt.format)
+			if err != nil {
+				t.Fatalf("newPrinter(%q) returned error: %v", tt.format, err)
+			}
+			var buf bytes.Buffer
+			if err := p(&buf, build); err != nil {
+				t.Fatalf("printer returned error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("output = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestNewPrinter_TemplateErrors tests that template errors are reported rather than printed
+func TestNewPrinter_TemplateErrors(t *testing.T) {
+	for _, format := range []string{"go-template", "go-template={{.Number", "jsonpath={.number", "go-template-file=/does/not/exist"} {
+		t.Run(format, func(t *testing.T) {
+			if _, err := newPrinter(format); classifyError(err) != kindUsage {
+				t.Errorf("newPrinter(%q) = %v, want a usage error", format, err)
+			}
+		})
+	}
+
+	p, err := newPrinter("go-template={{.NoSuchField}}")
+	if err != nil {
+		t.Fatalf("newPrinter returned error: %v", err)
+	}
+	if err := p(&bytes.Buffer{}, &buildDetail{}); err == nil || !strings.Contains(err.Error(), "failed to execute go-template") {
+		t.Errorf("Expected execution error, got: %v", err)
+	}
+}
+
+// TestRun_InvalidOutput tests that an invalid --output is reported by run before contacting Jenkins
+func TestRun_InvalidOutput(t *testing.T) {
+	old := output
+	output = "go-template={{.Number"
+	defer func() { output = old }()
+
+	err := run(context.Background(), []string{"get-build", "app", "42"})
+	if err == nil || !strings.Contains(err.Error(), "invalid go-template") {
+		t.Errorf("Expected 'invalid go-template' error, got: %v", err)
+	}
+}
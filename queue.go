@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// queueInterval is how often a queue item is polled while waiting for it to start a build
+var queueInterval = 2 * time.Second
+
+// queueItemResponse is a queue item as returned by /queue/api/json and /queue/item/N/api/json
+type queueItemResponse struct {
+	ID        int64  `json:"id"`
+	Why       string `json:"why"`
+	Blocked   bool   `json:"blocked"`
+	Buildable bool   `json:"buildable"`
+	Stuck     bool   `json:"stuck"`
+	Cancelled bool   `json:"cancelled"`
+	// InQueueSince is when the item was queued, in milliseconds since the epoch
+	InQueueSince int64 `json:"inQueueSince"`
+	Task         struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"task"`
+	// Executable is the build started for the item, once it has left the queue
+	Executable *struct {
+		Number int64  `json:"number"`
+		URL    string `json:"url"`
+	} `json:"executable"`
+}
+
+// queueItem is the result of getting a queue item
+type queueItem struct {
+	ID int64 `json:"id"`
+	// Job is the full path of the queued job
+	Job       string `json:"job"`
+	JobURL    string `json:"jobUrl,omitempty"`
+	Why       string `json:"why,omitempty"`
+	Blocked   bool   `json:"blocked"`
+	Buildable bool   `json:"buildable"`
+	Stuck     bool   `json:"stuck"`
+	Cancelled bool   `json:"cancelled,omitempty"`
+	// InQueueSince is when the item was queued
+	InQueueSince time.Time `json:"inQueueSince,omitzero"`
+	// InQueueFor is how long the item has been waiting in milliseconds, while it is still queued
+	InQueueFor float64 `json:"inQueueFor,omitempty"`
+	// Build is the build started for the item, once it has left the queue
+	Build *buildRef `json:"build,omitempty"`
+}
+
+// queueList is the result of listing the build queue
+type queueList struct {
+	Items []queueItem `json:"items"`
+}
+
+// Status summarizes the state of a queue item: STARTED, CANCELLED, STUCK, BLOCKED, BUILDABLE or WAITING
+func (q *queueItem) Status() string {
+	switch {
+	case q.Build != nil:
+		return "STARTED"
+	case q.Cancelled:
+		return "CANCELLED"
+	case q.Stuck:
+		return "STUCK"
+	case q.Blocked:
+		return "BLOCKED"
+	case q.Buildable:
+		return "BUILDABLE"
+	default:
+		return "WAITING"
+	}
+}
+
+func (q *queueItem) writeText(w io.Writer) {
+	printField(w, "Queue Item", q.ID)
+	printField(w, "Job", q.Job)
+	printField(w, "Status", q.Status())
+	if q.Why != "" {
+		printField(w, "Why", q.Why)
+	}
+	if q.InQueueFor > 0 {
+		printField(w, "In Queue", formatDuration(q.InQueueFor))
+	}
+	if q.Build != nil {
+		printField(w, "Build", fmt.Sprintf("#%d (%s)", q.Build.Number, q.Build.URL))
+	}
+}
+
+func (l *queueList) writeText(w io.Writer) {
+	if len(l.Items) == 0 {
+		fmt.Fprintln(w, "The build queue is empty")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d queued item(s):\n\n", len(l.Items))
+	for _, item := range l.Items {
+		inQueue := ""
+		if item.InQueueFor > 0 {
+			inQueue = formatDuration(item.InQueueFor)
+		}
+		fmt.Fprintf(w, "#%-7d %-40s %-10s %-12s %s\n", item.ID, item.Job, item.Status(), inQueue, item.Why)
+	}
+}
+
+// fetchQueue lists the items waiting in the build queue, longest waiting first
+func fetchQueue(ctx context.Context, client *gojenkins.Jenkins) (*queueList, error) {
+	var resp struct {
+		Items []queueItemResponse `json:"items"`
+	}
+	if err := getJSON(ctx, client, "/queue", &resp, nil); err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+
+	list := &queueList{Items: []queueItem{}}
+	for _, item := range resp.Items {
+		list.Items = append(list.Items, newQueueItem(item))
+	}
+	// Jenkins lists the most recently queued items first
+	for i, j := 0, len(list.Items)-1; i < j; i, j = i+1, j-1 {
+		list.Items[i], list.Items[j] = list.Items[j], list.Items[i]
+	}
+	return list, nil
+}
+
+// fetchQueueItem gets a queue item by its id. Jenkins keeps items for a few minutes after they leave the queue.
+func fetchQueueItem(ctx context.Context, client *gojenkins.Jenkins, id int64) (*queueItem, error) {
+	var resp queueItemResponse
+	if err := getJSON(ctx, client, "/queue/item/"+strconv.FormatInt(id, 10), &resp, nil); err != nil {
+		return nil, fmt.Errorf("failed to get queue item %d: %w", id, err)
+	}
+	item := newQueueItem(resp)
+	return &item, nil
+}
+
+// waitForQueueItem polls a queue item every interval until it starts a build.
+// Each time the reason the item is waiting changes, it is written to stderr.
+func waitForQueueItem(ctx context.Context, client *gojenkins.Jenkins, id int64, interval time.Duration) (*queueItem, error) {
+	why := ""
+	for {
+		item, err := fetchQueueItem(ctx, client, id)
+		if err != nil {
+			return nil, err
+		}
+		if item.Cancelled {
+			return nil, fmt.Errorf("queue item %d was cancelled", id)
+		}
+		if item.Build != nil {
+			return item, nil
+		}
+		if item.Why != "" && item.Why != why {
+			why = item.Why
+			fmt.Fprintf(os.Stderr, "Waiting in queue: %s\n", why)
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// awaitQueueItem waits up to timeout, or forever if it is zero, for a queue item to start a build.
+// If the timeout is reached first, the item is returned as last seen, without a build.
+func awaitQueueItem(ctx context.Context, client *gojenkins.Jenkins, id int64, interval, timeout time.Duration) (*queueItem, error) {
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	item, err := waitForQueueItem(waitCtx, client, id, interval)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return fetchQueueItem(ctx, client, id)
+	}
+	return item, err
+}
+
+// parseQueueID parses a queue item id, given as a number (optionally prefixed with "#") or as its URL
+func parseQueueID(s string) (int64, error) {
+	idStr := strings.TrimPrefix(s, "#")
+	if strings.Contains(s, "/queue/item/") {
+		idStr = path.Base(strings.TrimSuffix(s, "/"))
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid queue item: %s", s)
+	}
+	return id, nil
+}
+
+func newQueueItem(resp queueItemResponse) queueItem {
+	item := queueItem{
+		ID:        resp.ID,
+		Job:       jobNameFromURL(resp.Task.URL),
+		JobURL:    resp.Task.URL,
+		Why:       resp.Why,
+		Blocked:   resp.Blocked,
+		Buildable: resp.Buildable,
+		Stuck:     resp.Stuck,
+		Cancelled: resp.Cancelled,
+	}
+	if item.Job == "" {
+		item.Job = resp.Task.Name
+	}
+	if resp.InQueueSince != 0 {
+		item.InQueueSince = time.UnixMilli(resp.InQueueSince)
+	}
+	if resp.Executable != nil && resp.Executable.Number > 0 {
+		item.Build = &buildRef{Number: resp.Executable.Number, URL: resp.Executable.URL}
+	} else if !item.Cancelled && !item.InQueueSince.IsZero() {
+		item.InQueueFor = float64(time.Since(item.InQueueSince).Milliseconds())
+	}
+	return item
+}
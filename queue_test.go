@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFetchQueue tests that queue items are listed longest waiting first, with their full job path and status
+func TestFetchQueue(t *testing.T) {
+	queuedAt := time.Now().Add(-5 * time.Minute).UnixMilli()
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"items":[`+
+				`{"id":43,"why":"Waiting for next available executor","buildable":true,"inQueueSince":%d,"task":{"name":"main","url":"http://jenkins/job/team/job/service/job/main/"}},`+
+				`{"id":42,"why":"Build #7 is already in progress","blocked":true,"stuck":true,"inQueueSince":%d,"task":{"name":"app","url":"http://jenkins/job/app/"}}]}`,
+				queuedAt, queuedAt)
+		},
+	})
+
+	queue, err := fetchQueue(context.Background(), client)
+	if err != nil {
+		t.Fatalf("fetchQueue returned error: %v", err)
+	}
+	if len(queue.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(queue.Items))
+	}
+	first, second := queue.Items[0], queue.Items[1]
+	if first.ID != 42 || first.Job != "app" || first.Status() != "STUCK" {
+		t.Errorf("Unexpected first item: %+v (status %s)", first, first.Status())
+	}
+	if second.ID != 43 || second.Job != "team/service/main" || second.Status() != "BUILDABLE" {
+		t.Errorf("Unexpected second item: %+v (status %s)", second, second.Status())
+	}
+	if first.InQueueFor < float64(5*time.Minute/time.Millisecond) {
+		t.Errorf("Expected item to be queued for at least 5 minutes, got %vms", first.InQueueFor)
+	}
+}
+
+// TestWaitForQueueItem_Cancelled tests that a cancelled queue item is reported as an error
+func TestWaitForQueueItem_Cancelled(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/item/42/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":42,"cancelled":true}`))
+		},
+	})
+
+	_, err := waitForQueueItem(context.Background(), client, 42, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancelled error, got: %v", err)
+	}
+}
+
+// TestAwaitQueueItem_Timeout tests that an item still waiting at the timeout is returned without a build
+func TestAwaitQueueItem_Timeout(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/item/42/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":42,"why":"Waiting for next available executor","task":{"name":"app","url":"http://jenkins/job/app/"}}`))
+		},
+	})
+
+	item, err := awaitQueueItem(context.Background(), client, 42, time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("awaitQueueItem returned error: %v", err)
+	}
+	if item.Build != nil || item.Status() != "WAITING" {
+		t.Errorf("Expected item still waiting, got %+v", item)
+	}
+}
+
+// TestParseQueueID tests parsing queue item ids and URLs
+func TestParseQueueID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{"#42", 42, false},
+		{"https://jenkins.example.com/queue/item/42/", 42, false},
+		{"https://jenkins.example.com/queue/item/42", 42, false},
+		{"0", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseQueueID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQueueID(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseQueueID(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestJobNameFromURL tests converting job URLs to full job paths
+func TestJobNameFromURL(t *testing.T) {
+	tests := map[string]string{
+		"http://jenkins/job/app/":                       "app",
+		"http://jenkins/jenkins/job/team/job/service/":  "team/service",
+		"http://jenkins/job/team/job/service/job/main/": "team/service/main",
+		"http://jenkins/view/all/":                      "",
+	}
+	for url, want := range tests {
+		if got := jobNameFromURL(url); got != want {
+			t.Errorf("jobNameFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+// TestListQueueHandler tests that the list_queue tool returns the queue
+func TestListQueueHandler(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"items":[{"id":42,"why":"Waiting for next available executor","buildable":true,"task":{"name":"app","url":"http://jenkins/job/app/"}}]}`))
+		},
+	})
+
+	result := callTestTool(t, client, "list_queue", nil)
+	if result.IsError {
+		t.Fatalf("list_queue failed: %+v", result)
+	}
+	queue, ok := result.StructuredContent.(*queueList)
+	if !ok || len(queue.Items) != 1 || queue.Items[0].Job != "app" {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+}
+
+// TestGetQueueItemHandler tests that the get_queue_item tool accepts an id or URL and rejects anything else
+func TestGetQueueItemHandler(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/item/42/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":42,"blocked":true,"task":{"name":"app","url":"http://jenkins/job/app/"}}`))
+		},
+	})
+
+	result := callTestTool(t, client, "get_queue_item", map[string]any{"id": "https://jenkins.example.com/queue/item/42/"})
+	if result.IsError {
+		t.Fatalf("get_queue_item failed: %+v", result)
+	}
+	item, ok := result.StructuredContent.(*queueItem)
+	if !ok || item.ID != 42 || item.Status() != "BLOCKED" {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	if result := callTestTool(t, client, "get_queue_item", map[string]any{"id": "abc"}); !result.IsError {
+		t.Errorf("Expected an error for an invalid id, got %+v", result)
+	}
+}
+
+// TestWaitQueueItemHandler tests that the wait_queue_item tool returns the build once the item leaves the queue
+func TestWaitQueueItemHandler(t *testing.T) {
+	polls := 0
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/queue/item/42/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"id":42,"why":"Waiting for next available executor","task":{"name":"app","url":"http://jenkins/job/app/"}}`))
+				return
+			}
+			w.Write([]byte(`{"id":42,"task":{"name":"app","url":"http://jenkins/job/app/"},"executable":{"number":7,"url":"http://jenkins/job/app/7/"}}`))
+		},
+	})
+
+	result := callTestTool(t, client, "wait_queue_item", map[string]any{"id": "42", "interval": "1ms", "timeout": "1m"})
+	if result.IsError {
+		t.Fatalf("wait_queue_item failed: %+v", result)
+	}
+	item, ok := result.StructuredContent.(*queueItem)
+	if !ok || item.Build == nil || item.Build.Number != 7 || item.Status() != "STARTED" {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	if result := callTestTool(t, client, "wait_queue_item", map[string]any{"id": "42", "interval": "soon"}); !result.IsError {
+		t.Errorf("Expected an error for an invalid interval, got %+v", result)
+	}
+}
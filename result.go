@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	"github.com/dustin/go-humanize"
+)
+
+// result is a value produced by a command, rendered by the CLI printers and the MCP handlers alike
+type result interface {
+	// writeText writes the human-readable form of the result
+	writeText(w io.Writer)
+}
+
+// jobSummary is a job as it appears in a listing
+type jobSummary struct {
+	Name     string `json:"name"`
+	Status   string `json:"status,omitempty"`
+	Building bool   `json:"building,omitempty"`
+	URL      string `json:"url"`
+}
+
+// jobList is the result of listing jobs
+type jobList struct {
+	Jobs []jobSummary `json:"jobs"`
+}
+
+// buildRef is a reference to a build from a job
+type buildRef struct {
+	Number int64  `json:"number"`
+	Result string `json:"result,omitempty"`
+	URL    string `json:"url"`
+}
+
+// jobDetail is the result of getting a single job
+type jobDetail struct {
+	Name                string       `json:"name"`
+	URL                 string       `json:"url"`
+	Status              string       `json:"status,omitempty"`
+	Description         string       `json:"description,omitempty"`
+	LastBuild           *buildRef    `json:"lastBuild,omitempty"`
+	LastSuccessfulBuild *buildRef    `json:"lastSuccessfulBuild,omitempty"`
+	LastFailedBuild     *buildRef    `json:"lastFailedBuild,omitempty"`
+	InnerJobs           []jobSummary `json:"innerJobs,omitempty"`
+}
+
+// buildDetail is the result of getting a single build
+type buildDetail struct {
+	Number      int64  `json:"number"`
+	URL         string `json:"url"`
+	Result      string `json:"result,omitempty"`
+	Building    bool   `json:"building"`
+	Description string `json:"description,omitempty"`
+	// Timestamp is when the build started
+	Timestamp time.Time `json:"timestamp,omitzero"`
+	// Duration is the build duration in milliseconds, as reported by Jenkins
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// buildLog is the console output of a build
+type buildLog struct {
+	Job    string `json:"job"`
+	Number int64  `json:"number"`
+	Log    string `json:"log"`
+}
+
+// Status returns the build result, or BUILDING while the build is running
+func (b *buildDetail) Status() string {
+	if b.Building {
+		return "BUILDING"
+	}
+	return b.Result
+}
+
+func (l *jobList) writeText(w io.Writer) {
+	if len(l.Jobs) == 0 {
+		fmt.Fprintln(w, "No jobs found")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d job(s):\n\n", len(l.Jobs))
+	for _, job := range l.Jobs {
+		fmt.Fprintf(w, "%-40s %-15s %s\n", job.Name, job.Status, job.URL)
+	}
+}
+
+func (j *jobDetail) writeText(w io.Writer) {
+	printField(w, "Job Name", j.Name)
+	printField(w, "URL", j.URL)
+	// Only show status if it's not empty
+	if j.Status != "" {
+		printField(w, "Status", j.Status)
+	}
+	if j.Description != "" {
+		printField(w, "Description", j.Description)
+	}
+	if j.LastBuild != nil {
+		printField(w, "Last Build", fmt.Sprintf("#%d - %s (%s)", j.LastBuild.Number, j.LastBuild.Result, j.LastBuild.URL))
+	}
+	if j.LastSuccessfulBuild != nil {
+		printField(w, "Last Success", fmt.Sprintf("#%d (%s)", j.LastSuccessfulBuild.Number, j.LastSuccessfulBuild.URL))
+	}
+	if j.LastFailedBuild != nil {
+		printField(w, "Last Failed", fmt.Sprintf("#%d (%s)", j.LastFailedBuild.Number, j.LastFailedBuild.URL))
+	}
+	if len(j.InnerJobs) > 0 {
+		fmt.Fprintf(w, "\nInner Jobs (%d):\n", len(j.InnerJobs))
+		for _, innerJob := range j.InnerJobs {
+			fmt.Fprintf(w, "  %-38s %-15s %s\n", innerJob.Name, innerJob.Status, innerJob.URL)
+		}
+	}
+}
+
+func (b *buildDetail) writeText(w io.Writer) {
+	printField(w, "Build Number", b.Number)
+	printField(w, "URL", b.URL)
+	printField(w, "Status", b.Status())
+	if b.Description != "" {
+		printField(w, "Description", b.Description)
+	}
+	if !b.Timestamp.IsZero() {
+		printField(w, "Started", fmt.Sprintf("%s (%s)", b.Timestamp.Format("2006-01-02 15:04:05"), humanize.Time(b.Timestamp)))
+	}
+	if b.Duration > 0 {
+		printField(w, "Duration", formatDuration(b.Duration))
+	}
+}
+
+func (l *buildLog) writeText(w io.Writer) {
+	fmt.Fprint(w, l.Log)
+}
+
+// fetchJob gets the details of a job, including its notable builds and inner jobs
+func fetchJob(ctx context.Context, client *gojenkins.Jenkins, jobName string) (*jobDetail, error) {
+	job, err := lookupJob(ctx, client, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &jobDetail{
+		Name:        job.GetName(),
+		URL:         job.Raw.URL,
+		Status:      getStatusFromColor(job.Raw.Color),
+		Description: job.GetDescription(),
+		// Inner jobs exist for folders and multi-branch pipelines
+		InnerJobs: summarizeJobs(job.GetInnerJobsMetadata()),
+	}
+
+	lastBuild, err := job.GetLastBuild(ctx)
+	if err == nil && lastBuild != nil {
+		detail.LastBuild = newBuildRef(lastBuild)
+	}
+
+	lastSuccess, err := job.GetLastSuccessfulBuild(ctx)
+	if err == nil && lastSuccess != nil {
+		detail.LastSuccessfulBuild = newBuildRef(lastSuccess)
+	}
+
+	lastFailed, err := job.GetLastFailedBuild(ctx)
+	if err == nil && lastFailed != nil {
+		detail.LastFailedBuild = newBuildRef(lastFailed)
+	}
+
+	return detail, nil
+}
+
+// fetchBuild gets the details of a build
+func fetchBuild(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string) (*buildDetail, error) {
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+	return newBuildDetail(build), nil
+}
+
+// fetchBuildLog gets the console output of a build, keeping only the parts selected by the filter. The log is
+// streamed, so that only the selected parts are kept in memory: reading stops once the lines for Head are read,
+// and only the end of the log is read for Tail alone.
+func fetchBuildLog(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string, filter logFilter) (*buildLog, error) {
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+	path := build.Base + "/logText/progressiveText"
+
+	var log string
+	if filter.Tail > 0 && filter.Grep == nil && !filter.Errors {
+		log, err = readLogTail(ctx, client, path, filter.Tail)
+	} else {
+		log, err = readLog(ctx, client, path, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &buildLog{Job: jobName, Number: build.GetBuildNumber(), Log: log}, nil
+}
+
+// lookupBuild resolves a job name and build number or symbolic reference to a build
+func lookupBuild(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string) (*gojenkins.Build, error) {
+	job, err := lookupJob(ctx, client, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	buildNum, err := resolveBuildNumber(job.Raw, buildNumber, func() ([]gojenkins.JobBuild, error) {
+		return fetchAllBuilds(ctx, client, jobName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	build, err := getJenkinsBuild(ctx, jobName, job, buildNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build: %w", err)
+	}
+	return build, nil
+}
+
+// summarizeJobs converts job metadata into summaries, filtering out disabled jobs
+func summarizeJobs(jobs []gojenkins.InnerJob) []jobSummary {
+	summaries := []jobSummary{}
+	for _, job := range jobs {
+		if strings.HasPrefix(job.Color, "disabled") {
+			continue
+		}
+		summaries = append(summaries, newJobSummary(job))
+	}
+	return summaries
+}
+
+func newJobSummary(job gojenkins.InnerJob) jobSummary {
+	return jobSummary{Name: job.Name, Status: getStatusFromColor(job.Color), Building: isBuildingColor(job.Color), URL: job.Url}
+}
+
+func newBuildRef(build *gojenkins.Build) *buildRef {
+	return &buildRef{Number: build.GetBuildNumber(), Result: build.GetResult(), URL: build.GetUrl()}
+}
+
+func newBuildDetail(build *gojenkins.Build) *buildDetail {
+	detail := &buildDetail{
+		Number:   build.GetBuildNumber(),
+		URL:      build.GetUrl(),
+		Result:   build.GetResult(),
+		Building: build.Raw.Building,
+		Duration: build.GetDuration(),
+	}
+	if description, ok := build.Raw.Description.(string); ok {
+		detail.Description = description
+	}
+	if build.Raw.Timestamp != 0 {
+		detail.Timestamp = build.GetTimestamp()
+	}
+	return detail
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// htmlTagPattern matches the console note markup that wfapi/log includes in the log text
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stage is a pipeline stage as reported by the Pipeline Stage View plugin
+type stage struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// StartTime is when the stage started
+	StartTime time.Time `json:"startTime,omitzero"`
+	// Duration is the stage duration in milliseconds, including the time it was paused
+	Duration float64 `json:"duration"`
+	// PauseDuration is how long the stage waited, e.g. for input or an executor, in milliseconds
+	PauseDuration float64 `json:"pauseDuration,omitempty"`
+}
+
+// stageList is the result of getting the stages of a pipeline build
+type stageList struct {
+	Job    string  `json:"job"`
+	Number int64   `json:"number"`
+	Status string  `json:"status"`
+	Stages []stage `json:"stages"`
+}
+
+// stageLog is the log of a single pipeline stage
+type stageLog struct {
+	Job    string `json:"job"`
+	Number int64  `json:"number"`
+	Stage  string `json:"stage"`
+	Log    string `json:"log"`
+}
+
+func (l *stageList) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Build #%d: %s\n\n", l.Number, l.Status)
+	if len(l.Stages) == 0 {
+		fmt.Fprintln(w, "No stages found")
+		return
+	}
+	for _, s := range l.Stages {
+		paused := ""
+		if s.PauseDuration > 0 {
+			paused = "paused " + formatDuration(s.PauseDuration)
+		}
+		fmt.Fprintf(w, "%-30s %-22s %-12s %s\n", s.Name, s.Status, formatDuration(s.Duration), paused)
+	}
+}
+
+func (l *stageLog) writeText(w io.Writer) {
+	fmt.Fprint(w, l.Log)
+}
+
+// wfapiNode is a run, stage or step as returned by the wfapi/describe endpoints
+type wfapiNode struct {
+	ID                  string      `json:"id"`
+	Name                string      `json:"name"`
+	Status              string      `json:"status"`
+	StartTimeMillis     int64       `json:"startTimeMillis"`
+	DurationMillis      float64     `json:"durationMillis"`
+	PauseDurationMillis float64     `json:"pauseDurationMillis"`
+	Stages              []wfapiNode `json:"stages"`
+	StageFlowNodes      []wfapiNode `json:"stageFlowNodes"`
+	Links               struct {
+		Log *struct {
+			Href string `json:"href"`
+		} `json:"log"`
+	} `json:"_links"`
+}
+
+// fetchStages gets the stages of a pipeline build from wfapi/describe
+func fetchStages(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber string) (*stageList, error) {
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var run wfapiNode
+	if err := getRawJSON(ctx, client, build.Base+"/wfapi/describe", &run); err != nil {
+		return nil, fmt.Errorf("failed to get stages (is this a pipeline build?): %w", err)
+	}
+
+	list := &stageList{Job: jobName, Number: build.GetBuildNumber(), Status: run.Status, Stages: []stage{}}
+	for _, node := range run.Stages {
+		s := stage{
+			ID:            node.ID,
+			Name:          node.Name,
+			Status:        node.Status,
+			Duration:      node.DurationMillis,
+			PauseDuration: node.PauseDurationMillis,
+		}
+		if node.StartTimeMillis != 0 {
+			s.StartTime = time.UnixMilli(node.StartTimeMillis)
+		}
+		list.Stages = append(list.Stages, s)
+	}
+	return list, nil
+}
+
+// fetchStageLog gets the log of a pipeline stage, named case-insensitively, by concatenating the logs of its steps
+func fetchStageLog(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber, stageName string) (*stageLog, error) {
+	stages, err := fetchStages(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+	var found *stage
+	var names []string
+	for i, s := range stages.Stages {
+		names = append(names, s.Name)
+		if strings.EqualFold(s.Name, stageName) {
+			found = &stages.Stages[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, notFoundErrorf("stage not found: %s (stages: %s)", stageName, strings.Join(names, ", "))
+	}
+
+	nodeBase := jobPath(jobName) + "/" + strconv.FormatInt(stages.Number, 10) + "/execution/node/"
+	var node wfapiNode
+	if err := getRawJSON(ctx, client, nodeBase+found.ID+"/wfapi/describe", &node); err != nil {
+		return nil, fmt.Errorf("failed to get stage %s: %w", found.Name, err)
+	}
+
+	var log strings.Builder
+	for _, step := range node.StageFlowNodes {
+		if step.Links.Log == nil {
+			continue
+		}
+		var stepLog struct {
+			Text string `json:"text"`
+		}
+		if err := getRawJSON(ctx, client, nodeBase+step.ID+"/wfapi/log", &stepLog); err != nil {
+			return nil, fmt.Errorf("failed to get log of stage %s: %w", found.Name, err)
+		}
+		text := html.UnescapeString(htmlTagPattern.ReplaceAllString(stepLog.Text, ""))
+		log.WriteString(text)
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			log.WriteString("\n")
+		}
+	}
+	return &stageLog{Job: jobName, Number: stages.Number, Stage: found.Name, Log: log.String()}, nil
+}
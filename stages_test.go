@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bndr/gojenkins"
+)
+
+// newStagesTestJenkins serves build #7 of a pipeline whose Test stage failed
+func newStagesTestJenkins(t *testing.T) *gojenkins.Jenkins {
+	t.Helper()
+	return newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"result":"FAILURE","building":false}`))
+		},
+		"/job/app/7/wfapi/describe/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"7","status":"FAILED","stages":[` +
+				`{"id":"6","name":"Build","status":"SUCCESS","startTimeMillis":1704164645000,"durationMillis":65000,"pauseDurationMillis":0},` +
+				`{"id":"12","name":"Test","status":"FAILED","startTimeMillis":1704164710000,"durationMillis":125000,"pauseDurationMillis":30000}]}`))
+		},
+		"/job/app/7/execution/node/12/wfapi/describe/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"12","name":"Test","stageFlowNodes":[` +
+				`{"id":"13","name":"Shell Script","_links":{"log":{"href":"/job/app/7/execution/node/13/wfapi/log"}}},` +
+				`{"id":"14","name":"Error signal"}]}`))
+		},
+		"/job/app/7/execution/node/13/wfapi/log/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"nodeId":"13","text":"+ go test ./...\n<span class=\"timestamp\">--- FAIL: TestApp</span>\nexit code &gt; 0"}`))
+		},
+	})
+}
+
+// TestFetchStages tests that each stage is listed with its status, duration and pause time
+func TestFetchStages(t *testing.T) {
+	client := newStagesTestJenkins(t)
+
+	stages, err := fetchStages(context.Background(), client, "app", "7")
+	if err != nil {
+		t.Fatalf("fetchStages returned error: %v", err)
+	}
+	if stages.Number != 7 || stages.Status != "FAILED" || len(stages.Stages) != 2 {
+		t.Fatalf("Unexpected stages: %+v", stages)
+	}
+	test := stages.Stages[1]
+	if test.Name != "Test" || test.Status != "FAILED" || test.Duration != 125000 || test.PauseDuration != 30000 {
+		t.Errorf("Unexpected Test stage: %+v", test)
+	}
+
+	var buf bytes.Buffer
+	stages.writeText(&buf)
+	if !strings.Contains(buf.String(), "Test") || !strings.Contains(buf.String(), "paused 30 seconds") {
+		t.Errorf("Unexpected text output:\n%s", buf.String())
+	}
+}
+
+// TestFetchStageLog tests that a stage's log is assembled from its steps' logs, without console markup
+func TestFetchStageLog(t *testing.T) {
+	client := newStagesTestJenkins(t)
+
+	log, err := fetchStageLog(context.Background(), client, "app", "7", "test")
+	if err != nil {
+		t.Fatalf("fetchStageLog returned error: %v", err)
+	}
+	if want := "+ go test ./...\n--- FAIL: TestApp\nexit code > 0\n"; log.Log != want {
+		t.Errorf("Expected log %q, got %q", want, log.Log)
+	}
+	if log.Stage != "Test" {
+		t.Errorf("Expected stage Test, got %q", log.Stage)
+	}
+
+	if _, err := fetchStageLog(context.Background(), client, "app", "7", "Deploy"); err == nil || !strings.Contains(err.Error(), "stages: Build, Test") {
+		t.Errorf("Expected stage not found error listing the stages, got: %v", err)
+	}
+}
+
+// TestGetBuildStagesHandler tests that the get_build_stages tool lists the stages, or returns one stage's log when given a stage
+func TestGetBuildStagesHandler(t *testing.T) {
+	client := newStagesTestJenkins(t)
+
+	result := callTestTool(t, client, "get_build_stages", map[string]any{"job_name": "app", "build_number": "7"})
+	if result.IsError {
+		t.Fatalf("get_build_stages failed: %+v", result)
+	}
+	stages, ok := result.StructuredContent.(*stageList)
+	if !ok || stages.Number != 7 || len(stages.Stages) != 2 {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	result = callTestTool(t, client, "get_build_stages", map[string]any{"job_name": "app", "build_number": "7", "stage": "Test"})
+	if result.IsError {
+		t.Fatalf("get_build_stages with a stage failed: %+v", result)
+	}
+	log, ok := result.StructuredContent.(*stageLog)
+	if !ok || log.Stage != "Test" || !strings.Contains(log.Log, "--- FAIL: TestApp") {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	if result := callTestTool(t, client, "get_build_stages", map[string]any{"job_name": "app", "build_number": "7", "stage": "Deploy"}); !result.IsError {
+		t.Errorf("Expected an error for an unknown stage, got %+v", result)
+	}
+}
+
+// TestFetchStages_NotPipeline tests that builds without wfapi are reported as such
+func TestFetchStages_NotPipeline(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"result":"SUCCESS","building":false}`))
+		},
+	})
+
+	_, err := fetchStages(context.Background(), client, "app", "7")
+	if err == nil || !strings.Contains(err.Error(), "pipeline") {
+		t.Errorf("Expected not a pipeline error, got: %v", err)
+	}
+}
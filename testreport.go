@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// testReportTree is the tree query selecting the test report fields needed to summarize and re-emit it
+const testReportTree = "suites[name,duration,cases[className,name,duration,status,skipped,errorDetails,errorStackTrace]]"
+
+// testErrorLines is how many lines of a failing test's error details are shown in text output
+const testErrorLines = 5
+
+// testStackTraceLines is how many lines of a failing test's stack trace are included in structured output
+const testStackTraceLines = 20
+
+// testCase is a failing test case in a test report
+type testCase struct {
+	Suite     string `json:"suite"`
+	ClassName string `json:"className"`
+	Name      string `json:"name"`
+	// Status is FAILED, or REGRESSION if the test passed in the previous build
+	Status string `json:"status"`
+	// Duration is the test duration in milliseconds
+	Duration     float64 `json:"duration"`
+	ErrorDetails string  `json:"errorDetails,omitempty"`
+	// StackTrace is the start of the error stack trace
+	StackTrace string `json:"stackTrace,omitempty"`
+}
+
+// testReport is the result of getting the JUnit test report of a build
+type testReport struct {
+	Job       string     `json:"job"`
+	Number    int64      `json:"number"`
+	PassCount int        `json:"passCount"`
+	FailCount int        `json:"failCount"`
+	SkipCount int        `json:"skipCount"`
+	Failures  []testCase `json:"failures"`
+
+	// suites is the full report, kept to re-emit it as JUnit XML
+	suites []testSuiteResponse
+}
+
+// testSuiteResponse is a test suite as returned by testReport/api/json
+type testSuiteResponse struct {
+	Name string `json:"name"`
+	// Duration is in seconds
+	Duration float64 `json:"duration"`
+	Cases    []struct {
+		ClassName string `json:"className"`
+		Name      string `json:"name"`
+		// Duration is in seconds
+		Duration        float64 `json:"duration"`
+		Status          string  `json:"status"`
+		Skipped         bool    `json:"skipped"`
+		ErrorDetails    string  `json:"errorDetails"`
+		ErrorStackTrace string  `json:"errorStackTrace"`
+	} `json:"cases"`
+}
+
+func (r *testReport) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Build #%d: %d passed, %d failed, %d skipped\n", r.Number, r.PassCount, r.FailCount, r.SkipCount)
+	if len(r.Failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nFailed tests (%d):\n", len(r.Failures))
+	for _, c := range r.Failures {
+		fmt.Fprintf(w, "\n  %s.%s (%s, %s)\n", c.ClassName, c.Name, c.Status, formatDuration(c.Duration))
+		details := c.ErrorDetails
+		if details == "" {
+			details = c.StackTrace
+		}
+		for _, line := range firstLines(details, testErrorLines) {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+// failingTestStatus reports whether a test case status is a failure
+func failingTestStatus(status string) bool {
+	return status == "FAILED" || status == "REGRESSION"
+}
+
+// fetchTestReport gets the JUnit test report of a build, optionally only of the suites matching suitePattern,
+// a glob or a substring like job name patterns
+func fetchTestReport(ctx context.Context, client *gojenkins.Jenkins, jobName, buildNumber, suitePattern string) (*testReport, error) {
+	suiteFilter, err := compileJobPattern(suitePattern, false)
+	if err != nil {
+		return nil, err
+	}
+
+	build, err := lookupBuild(ctx, client, jobName, buildNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Suites []testSuiteResponse `json:"suites"`
+	}
+	if err := getJSON(ctx, client, build.Base+"/testReport", &resp, map[string]string{"tree": testReportTree}); err != nil {
+		return nil, fmt.Errorf("failed to get test report (does the build publish JUnit results?): %w", err)
+	}
+
+	report := &testReport{Job: jobName, Number: build.GetBuildNumber(), Failures: []testCase{}}
+	for _, suite := range resp.Suites {
+		if suiteFilter != nil && !suiteFilter.MatchString(suite.Name) {
+			continue
+		}
+		report.suites = append(report.suites, suite)
+		for _, c := range suite.Cases {
+			switch {
+			case c.Skipped || c.Status == "SKIPPED":
+				report.SkipCount++
+			case failingTestStatus(c.Status):
+				report.FailCount++
+				report.Failures = append(report.Failures, testCase{
+					Suite:        suite.Name,
+					ClassName:    c.ClassName,
+					Name:         c.Name,
+					Status:       c.Status,
+					Duration:     c.Duration * 1000,
+					ErrorDetails: c.ErrorDetails,
+					StackTrace:   strings.Join(firstLines(c.ErrorStackTrace, testStackTraceLines), "\n"),
+				})
+			default:
+				report.PassCount++
+			}
+		}
+	}
+	return report, nil
+}
+
+// firstLines returns up to n lines from the start of s, ignoring a trailing newline
+func firstLines(s string, n int) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitN(s, "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return lines
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the test report, with every case of the selected suites, as JUnit XML
+func (r *testReport) writeJUnit(w io.Writer) error {
+	root := junitTestSuites{Failures: r.FailCount, Skipped: r.SkipCount, Tests: r.PassCount + r.FailCount + r.SkipCount}
+	for _, suite := range r.suites {
+		out := junitTestSuite{Name: suite.Name, Time: suite.Duration, Tests: len(suite.Cases)}
+		for _, c := range suite.Cases {
+			tc := junitTestCase{ClassName: c.ClassName, Name: c.Name, Time: c.Duration}
+			switch {
+			case c.Skipped || c.Status == "SKIPPED":
+				tc.Skipped = &struct{}{}
+				out.Skipped++
+			case failingTestStatus(c.Status):
+				message := ""
+				if lines := firstLines(c.ErrorDetails, 1); len(lines) > 0 {
+					message = lines[0]
+				}
+				text := c.ErrorStackTrace
+				if text == "" {
+					text = c.ErrorDetails
+				}
+				tc.Failure = &junitFailure{Message: message, Text: text}
+				out.Failures++
+			}
+			out.Cases = append(out.Cases, tc)
+		}
+		root.Suites = append(root.Suites, out)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
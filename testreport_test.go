@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bndr/gojenkins"
+)
+
+// newTestReportJenkins serves build #7 of app with a test report of two suites
+func newTestReportJenkins(t *testing.T) *gojenkins.Jenkins {
+	t.Helper()
+	return newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"app","url":"http://jenkins/job/app/"}`))
+		},
+		"/job/app/7/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"number":7,"result":"UNSTABLE","building":false}`))
+		},
+		"/job/app/7/testReport/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"suites":[` +
+				`{"name":"com.example.ApiTest","duration":3.5,"cases":[` +
+				`{"className":"com.example.ApiTest","name":"testGet","duration":1.5,"status":"PASSED"},` +
+				`{"className":"com.example.ApiTest","name":"testPost","duration":2,"status":"REGRESSION",` +
+				`"errorDetails":"expected 201 but was 500\nline 2\nline 3\nline 4\nline 5\nline 6","errorStackTrace":"java.lang.AssertionError\n\tat ApiTest.testPost"},` +
+				`{"className":"com.example.ApiTest","name":"testDelete","status":"SKIPPED","skipped":true}]},` +
+				`{"name":"com.example.DbTest","duration":1,"cases":[` +
+				`{"className":"com.example.DbTest","name":"testConnect","duration":1,"status":"FAILED","errorDetails":"connection refused"}]}]}`))
+		},
+	})
+}
+
+// TestFetchTestReport tests that tests are counted and failures listed with their details
+func TestFetchTestReport(t *testing.T) {
+	client := newTestReportJenkins(t)
+
+	report, err := fetchTestReport(context.Background(), client, "app", "7", "")
+	if err != nil {
+		t.Fatalf("fetchTestReport returned error: %v", err)
+	}
+	if report.PassCount != 1 || report.FailCount != 2 || report.SkipCount != 1 {
+		t.Errorf("Expected 1 passed, 2 failed and 1 skipped, got %+v", report)
+	}
+	if len(report.Failures) != 2 {
+		t.Fatalf("Expected 2 failures, got %d", len(report.Failures))
+	}
+	failure := report.Failures[0]
+	if failure.Name != "testPost" || failure.Status != "REGRESSION" || failure.Duration != 2000 || failure.Suite != "com.example.ApiTest" {
+		t.Errorf("Unexpected failure: %+v", failure)
+	}
+
+	var buf bytes.Buffer
+	report.writeText(&buf)
+	text := buf.String()
+	if !strings.Contains(text, "Build #7: 1 passed, 2 failed, 1 skipped") || !strings.Contains(text, "com.example.ApiTest.testPost (REGRESSION, 2 seconds)") {
+		t.Errorf("Unexpected text output:\n%s", text)
+	}
+	if !strings.Contains(text, "line 5") || strings.Contains(text, "line 6") {
+		t.Errorf("Expected the first %d lines of error details, got:\n%s", testErrorLines, text)
+	}
+}
+
+// TestFetchTestReport_Suite tests that suites can be selected by name
+func TestFetchTestReport_Suite(t *testing.T) {
+	client := newTestReportJenkins(t)
+
+	report, err := fetchTestReport(context.Background(), client, "app", "7", "DbTest")
+	if err != nil {
+		t.Fatalf("fetchTestReport returned error: %v", err)
+	}
+	if report.PassCount != 0 || report.FailCount != 1 || report.SkipCount != 0 || report.Failures[0].Name != "testConnect" {
+		t.Errorf("Expected only the DbTest failure, got %+v", report)
+	}
+}
+
+// TestGetTestReportHandler tests that the get_test_report tool returns the report, limited to a suite when given one
+func TestGetTestReportHandler(t *testing.T) {
+	client := newTestReportJenkins(t)
+
+	result := callTestTool(t, client, "get_test_report", map[string]any{"job_name": "app", "build_number": "7"})
+	if result.IsError {
+		t.Fatalf("get_test_report failed: %+v", result)
+	}
+	report, ok := result.StructuredContent.(*testReport)
+	if !ok || report.Number != 7 || report.FailCount != 2 {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	result = callTestTool(t, client, "get_test_report", map[string]any{"job_name": "app", "build_number": "7", "suite": "DbTest"})
+	if result.IsError {
+		t.Fatalf("get_test_report with a suite failed: %+v", result)
+	}
+	report, ok = result.StructuredContent.(*testReport)
+	if !ok || report.FailCount != 1 || len(report.Failures) != 1 || report.Failures[0].Name != "testConnect" {
+		t.Errorf("Unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	if result := callTestTool(t, client, "get_test_report", map[string]any{"job_name": "app"}); !result.IsError {
+		t.Errorf("Expected an error without a build number, got %+v", result)
+	}
+}
+
+// TestPrintJUnit tests that a test report is re-emitted as JUnit XML
+func TestPrintJUnit(t *testing.T) {
+	client := newTestReportJenkins(t)
+
+	report, err := fetchTestReport(context.Background(), client, "app", "7", "")
+	if err != nil {
+		t.Fatalf("fetchTestReport returned error: %v", err)
+	}
+	p, err := newPrinter("junit")
+	if err != nil {
+		t.Fatalf("newPrinter returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := p(&buf, report); err != nil {
+		t.Fatalf("printer returned error: %v", err)
+	}
+
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if parsed.Tests != 4 || parsed.Failures != 2 || parsed.Skipped != 1 || len(parsed.Suites) != 2 {
+		t.Errorf("Unexpected totals: %+v", parsed)
+	}
+	api := parsed.Suites[0]
+	if api.Name != "com.example.ApiTest" || api.Tests != 3 || api.Failures != 1 || api.Skipped != 1 {
+		t.Errorf("Unexpected suite: %+v", api)
+	}
+	if f := api.Cases[1].Failure; f == nil || f.Message != "expected 201 but was 500" || !strings.Contains(f.Text, "AssertionError") {
+		t.Errorf("Unexpected failure: %+v", f)
+	}
+
+	// Other results cannot be written as JUnit XML
+	if err := p(&buf, &buildDetail{Number: 7}); err == nil {
+		t.Error("Expected error printing a build as JUnit XML")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+const (
+	// defaultTimeout is how long to wait for Jenkins to connect and start responding, unless configured
+	defaultTimeout = 30 * time.Second
+	// defaultRetries is how many times a failing GET is retried, unless configured
+	defaultRetries = 3
+	// maxRetryDelay caps the delay between retries, including one asked for by a Retry-After header
+	maxRetryDelay = 30 * time.Second
+)
+
+// retryDelay is the delay before the first retry, doubling with each retry after it
+var retryDelay = 500 * time.Millisecond
+
+// newHTTPClient creates the HTTP client used to talk to Jenkins, trusting the CA bundle and presenting the client
+// certificate of the TLS settings, and with the timeout, proxy and retries of the HTTP settings.
+// The timeout bounds connecting and waiting for the response headers, but not reading the body, so that streaming
+// a log or downloading a large artifact is not cut short.
+func newHTTPClient(tlsSettings config.TLS, httpSettings config.HTTP) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(tlsSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTimeout
+	if httpSettings.Timeout != "" {
+		timeout, err = time.ParseDuration(httpSettings.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout: %s", httpSettings.Timeout)
+		}
+	}
+	retries := defaultRetries
+	if httpSettings.Retries != nil {
+		retries = *httpSettings.Retries
+		if retries < 0 {
+			return nil, fmt.Errorf("retries must not be negative: %d", retries)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	if httpSettings.Proxy != "" {
+		proxy, err := neturl.Parse(httpSettings.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", httpSettings.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: &retryTransport{base: transport, retries: retries}}, nil
+}
+
+// newTLSConfig creates the TLS configuration of the TLS settings
+func newTLSConfig(settings config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file: %s", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (settings.ClientCert == "") != (settings.ClientKey == "") {
+		return nil, fmt.Errorf("a client certificate and key must be configured together")
+	}
+	if settings.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(settings.ClientCert, settings.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// retryTransport retries GET and HEAD requests failing with a 502, 503 or 504 status or a connection reset,
+// with exponential backoff. Other requests, such as triggering a build, are never retried as they may have
+// taken effect.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := delay
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = after
+			}
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), min(wait, maxRetryDelay)); err != nil {
+			return nil, err
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether a GET may succeed if retried: Jenkins or a proxy in front of it is briefly unavailable,
+// or dropped the connection
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay asked for by a response's Retry-After header in seconds, or zero
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// newTLSTestJenkins starts a Jenkins over HTTPS with a certificate no system trusts
+func newTLSTestJenkins(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"nodeName":"built-in","jobs":[]}`))
+	}))
+	srv.TLS = &tls.Config{}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestNewHTTPClient_TLS tests connecting to a server whose certificate is trusted through a CA file, or not verified
+func TestNewHTTPClient_TLS(t *testing.T) {
+	srv := newTLSTestJenkins(t)
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		settings config.TLS
+		wantErr  string
+	}{
+		{"untrusted", config.TLS{}, "certificate"},
+		{"ca file", config.TLS{CAFile: caFile}, ""},
+		{"insecure", config.TLS{InsecureSkipVerify: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient, err := newHTTPClient(tt.settings, config.HTTP{})
+			if err != nil {
+				t.Fatalf("newHTTPClient returned error: %v", err)
+			}
+			_, err = gojenkins.CreateJenkins(httpClient, srv.URL, "admin", "token").Init(ctx)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected to connect, got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestNewHTTPClient_ClientCertificate tests presenting a client certificate to a server requiring mutual TLS
+func TestNewHTTPClient_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "jenkins-cli"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writePEM(t, dir, "client.pem", "CERTIFICATE", certDER)
+	keyFile := writePEM(t, dir, "client-key.pem", "PRIVATE KEY", keyDER)
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"nodeName":"built-in","jobs":[]}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	httpClient, err := newHTTPClient(config.TLS{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile}, config.HTTP{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	if _, err := gojenkins.CreateJenkins(httpClient, srv.URL, "admin", "token").Init(context.Background()); err != nil {
+		t.Errorf("Expected to connect with the client certificate, got: %v", err)
+	}
+
+	httpClient, err = newHTTPClient(config.TLS{CAFile: caFile}, config.HTTP{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	if _, err := gojenkins.CreateJenkins(httpClient, srv.URL, "admin", "token").Init(context.Background()); err == nil {
+		t.Error("Expected the server to reject a connection without a client certificate")
+	}
+}
+
+// TestNewTLSConfig_Invalid tests that unusable TLS settings are rejected
+func TestNewTLSConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings config.TLS
+		wantErr  string
+	}{
+		{"missing CA file", config.TLS{CAFile: filepath.Join(dir, "missing.pem")}, "failed to read CA file"},
+		{"CA file without certificates", config.TLS{CAFile: notPEM}, "no certificates found"},
+		{"certificate without key", config.TLS{ClientCert: notPEM}, "configured together"},
+		{"invalid key pair", config.TLS{ClientCert: notPEM, ClientKey: notPEM}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTLSConfig(tt.settings); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestRetryTransport tests that GETs are retried on 502, 503 and 504 statuses and connection resets, but not POSTs
+func TestRetryTransport(t *testing.T) {
+	old := retryDelay
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = old }()
+
+	var attempts int
+	failures := 0
+	reset := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts > failures {
+			w.Write([]byte("ok"))
+			return
+		}
+		if reset {
+			// Close the connection with a TCP reset rather than a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.(*net.TCPConn).SetLinger(0)
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	httpClient, err := newHTTPClient(config.TLS{}, config.HTTP{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		failures     int
+		reset        bool
+		wantStatus   int
+		wantAttempts int
+	}{
+		{"recovers", http.MethodGet, 2, false, http.StatusOK, 3},
+		{"gives up", http.MethodGet, 10, false, http.StatusServiceUnavailable, defaultRetries + 1},
+		{"connection reset", http.MethodGet, 1, true, http.StatusOK, 2},
+		{"post not retried", http.MethodPost, 1, false, http.StatusServiceUnavailable, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, failures, reset = 0, tt.failures, tt.reset
+			req, _ := http.NewRequest(tt.method, srv.URL, nil)
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || attempts != tt.wantAttempts {
+				t.Errorf("Expected status %d after %d attempts, got %d after %d", tt.wantStatus, tt.wantAttempts, resp.StatusCode, attempts)
+			}
+		})
+	}
+}
+
+// TestNewHTTPClient_Timeout tests that a Jenkins that does not respond is given up on
+func TestNewHTTPClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	httpClient, err := newHTTPClient(config.TLS{}, config.HTTP{Timeout: "50ms"})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	start := time.Now()
+	_, err = httpClient.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to give up quickly, took %s", elapsed)
+	}
+}
+
+// TestNewHTTPClient_Proxy tests that requests go through the configured proxy
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("ok"))
+	}))
+	defer proxy.Close()
+
+	httpClient, err := newHTTPClient(config.TLS{}, config.HTTP{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	resp, err := httpClient.Get("http://jenkins.example.com/api/json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://jenkins.example.com/api/json" {
+		t.Errorf("Expected the request to go through the proxy, got %q", proxied)
+	}
+}
+
+// TestNewHTTPClient_Invalid tests that unusable HTTP settings are rejected
+func TestNewHTTPClient_Invalid(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name     string
+		settings config.HTTP
+		wantErr  string
+	}{
+		{"invalid timeout", config.HTTP{Timeout: "soon"}, "invalid timeout"},
+		{"negative retries", config.HTTP{Retries: &negative}, "must not be negative"},
+		{"invalid proxy", config.HTTP{Proxy: "not a url"}, "invalid proxy URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newHTTPClient(config.TLS{}, tt.settings); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// parameterDefinitionsTree is the tree query selecting a job's parameter definitions. They are listed under
+// property by current Jenkins versions and under actions by older ones.
+const parameterDefinitionsTree = "property[parameterDefinitions[name,type,choices]],actions[parameterDefinitions[name,type,choices]]"
+
+// buildParams collects the repeatable -p KEY=VALUE flag of build-job
+type buildParams map[string]string
+
+func (p buildParams) String() string {
+	pairs := make([]string, 0, len(p))
+	for key, value := range p {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p buildParams) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid parameter %q (expected KEY=VALUE)", s)
+	}
+	if _, dup := p[key]; dup {
+		return fmt.Errorf("parameter %s given more than once", key)
+	}
+	p[key] = value
+	return nil
+}
+
+// parameterDefinition is a build parameter declared by a job
+type parameterDefinition struct {
+	Name string `json:"name"`
+	// Type is the Jenkins class of the parameter, e.g. ChoiceParameterDefinition
+	Type    string   `json:"type"`
+	Choices []string `json:"choices"`
+}
+
+// fetchParameterDefinitions gets the build parameters declared by a job
+func fetchParameterDefinitions(ctx context.Context, client *gojenkins.Jenkins, jobName string) ([]parameterDefinition, error) {
+	var resp struct {
+		Property []struct {
+			ParameterDefinitions []parameterDefinition `json:"parameterDefinitions"`
+		} `json:"property"`
+		Actions []struct {
+			ParameterDefinitions []parameterDefinition `json:"parameterDefinitions"`
+		} `json:"actions"`
+	}
+	if err := getJSON(ctx, client, jobPath(jobName), &resp, map[string]string{"tree": parameterDefinitionsTree}); err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var defs []parameterDefinition
+	add := func(more []parameterDefinition) {
+		for _, def := range more {
+			if !slices.ContainsFunc(defs, func(d parameterDefinition) bool { return d.Name == def.Name }) {
+				defs = append(defs, def)
+			}
+		}
+	}
+	for _, property := range resp.Property {
+		add(property.ParameterDefinitions)
+	}
+	for _, action := range resp.Actions {
+		add(action.ParameterDefinitions)
+	}
+	return defs, nil
+}
+
+// validateBuildParams checks that every parameter is declared by the job, and that choice and boolean
+// parameters have one of their allowed values
+func validateBuildParams(defs []parameterDefinition, params buildParams) error {
+	if len(params) > 0 && len(defs) == 0 {
+		return fmt.Errorf("job does not take parameters")
+	}
+
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := params[key]
+		i := slices.Index(names, key)
+		if i < 0 {
+			return fmt.Errorf("unknown parameter %s (job parameters: %s)", key, strings.Join(names, ", "))
+		}
+		switch def := defs[i]; def.Type {
+		case "ChoiceParameterDefinition":
+			if len(def.Choices) > 0 && !slices.Contains(def.Choices, value) {
+				return fmt.Errorf("invalid value %q for parameter %s (must be one of %s)", value, key, strings.Join(def.Choices, ", "))
+			}
+		case "BooleanParameterDefinition":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("invalid value %q for parameter %s (must be true or false)", value, key)
+			}
+		}
+	}
+	return nil
+}
+
+// triggerBuild validates the parameters against the job's definitions, then queues a build of the job and
+// returns the id of its queue item. Parameterized jobs are always built with buildWithParameters, so that
+// parameters not given take their default values.
+func triggerBuild(ctx context.Context, client *gojenkins.Jenkins, jobName string, params buildParams) (int64, error) {
+	defs, err := fetchParameterDefinitions(ctx, client, jobName)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateBuildParams(defs, params); err != nil {
+		return 0, err
+	}
+
+	endpoint := "/build"
+	form := neturl.Values{}
+	if len(defs) > 0 {
+		endpoint = "/buildWithParameters"
+		for key, value := range params {
+			form.Set(key, value)
+		}
+	}
+	resp, err := client.Requester.Post(ctx, jobPath(jobName)+endpoint, strings.NewReader(form.Encode()), nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trigger build: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to trigger build: %w", newStatusError(resp))
+	}
+
+	// Jenkins returns the queue item in the Location header, e.g. https://jenkins/queue/item/42/
+	location := resp.Header.Get("Location")
+	if !strings.Contains(location, "/queue/item/") {
+		return 0, fmt.Errorf("failed to trigger build: no queue item in response")
+	}
+	return parseQueueID(location)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuildParams_Set tests parsing of the repeatable -p KEY=VALUE flag
+func TestBuildParams_Set(t *testing.T) {
+	params := buildParams{}
+	if err := params.Set("BRANCH=main"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := params.Set("ARGS=a=b"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if params["BRANCH"] != "main" || params["ARGS"] != "a=b" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+
+	for _, invalid := range []string{"BRANCH", "=main", "BRANCH=other"} {
+		if err := params.Set(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestValidateBuildParams tests that parameters are checked against the job's definitions
+func TestValidateBuildParams(t *testing.T) {
+	defs := []parameterDefinition{
+		{Name: "BRANCH", Type: "StringParameterDefinition"},
+		{Name: "ENV", Type: "ChoiceParameterDefinition", Choices: []string{"dev", "prod"}},
+		{Name: "DRY_RUN", Type: "BooleanParameterDefinition"},
+	}
+
+	tests := []struct {
+		name    string
+		defs    []parameterDefinition
+		params  buildParams
+		wantErr string
+	}{
+		{"none", defs, buildParams{}, ""},
+		{"valid", defs, buildParams{"BRANCH": "main", "ENV": "prod", "DRY_RUN": "true"}, ""},
+		{"unknown", defs, buildParams{"BRANCHES": "main"}, "unknown parameter BRANCHES (job parameters: BRANCH, ENV, DRY_RUN)"},
+		{"invalid choice", defs, buildParams{"ENV": "staging"}, `invalid value "staging" for parameter ENV (must be one of dev, prod)`},
+		{"invalid boolean", defs, buildParams{"DRY_RUN": "yes"}, `invalid value "yes" for parameter DRY_RUN (must be true or false)`},
+		{"not parameterized", nil, buildParams{"BRANCH": "main"}, "job does not take parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBuildParams(tt.defs, tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestTriggerBuild tests that a parameterized build is posted and its queue item tracked to a build number
+func TestTriggerBuild(t *testing.T) {
+	var form string
+	polls := 0
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/job/app/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"property":[{},{"parameterDefinitions":[` +
+				`{"name":"BRANCH","type":"StringParameterDefinition"},` +
+				`{"name":"ENV","type":"ChoiceParameterDefinition","choices":["dev","prod"]}]}]}`))
+		},
+		"/job/app/buildWithParameters": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected POST, got %s", r.Method)
+			}
+			r.ParseForm()
+			form = r.PostForm.Encode()
+			w.Header().Set("Location", "http://jenkins/queue/item/42/")
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/queue/item/42/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"id":42,"why":"Waiting for next available executor"}`))
+				return
+			}
+			w.Write([]byte(`{"id":42,"executable":{"number":8,"url":"http://jenkins/job/app/8/"}}`))
+		},
+	})
+
+	ctx := context.Background()
+	id, err := triggerBuild(ctx, client, "app", buildParams{"ENV": "prod"})
+	if err != nil {
+		t.Fatalf("triggerBuild returned error: %v", err)
+	}
+	if id != 42 {
+		t.Errorf("Expected queue item 42, got %d", id)
+	}
+	if form != "ENV=prod" {
+		t.Errorf("Expected form ENV=prod, got %q", form)
+	}
+
+	item, err := waitForQueueItem(ctx, client, id, time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForQueueItem returned error: %v", err)
+	}
+	if item.Build == nil || item.Build.Number != 8 || polls != 2 {
+		t.Errorf("Expected build 8 after 2 polls, got %+v after %d polls", item.Build, polls)
+	}
+
+	if _, err := triggerBuild(ctx, client, "app", buildParams{"ENV": "staging"}); err == nil || !strings.Contains(err.Error(), "invalid value") {
+		t.Errorf("Expected invalid value error, got: %v", err)
+	}
+}
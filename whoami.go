@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// configSetting is a resolved setting and where it came from: flag, env, config, keyring, netrc, file, command
+// or default
+type configSetting struct {
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// configView is the result of viewing the resolved configuration. The token is masked.
+type configView struct {
+	// Context is the context whose settings apply, if any
+	Context            string        `json:"context,omitempty"`
+	URL                configSetting `json:"url"`
+	Username           configSetting `json:"username"`
+	Token              configSetting `json:"token"`
+	AllowWrite         bool          `json:"allowWrite"`
+	CAFile             string        `json:"caFile,omitempty"`
+	ClientCert         string        `json:"clientCert,omitempty"`
+	InsecureSkipVerify bool          `json:"insecureSkipVerify,omitempty"`
+	Timeout            string        `json:"timeout,omitempty"`
+	Proxy              string        `json:"proxy,omitempty"`
+	Retries            *int          `json:"retries,omitempty"`
+}
+
+// whoAmI is the result of asking Jenkins who the resolved credentials authenticate as
+type whoAmI struct {
+	configView
+	// Name is the user Jenkins authenticated the credentials as
+	Name          string   `json:"name"`
+	Authenticated bool     `json:"authenticated"`
+	Anonymous     bool     `json:"anonymous"`
+	Authorities   []string `json:"authorities"`
+}
+
+func (v *configView) writeText(w io.Writer) {
+	if v.Context != "" {
+		printField(w, "Context", v.Context)
+	}
+	printField(w, "URL", describeSetting(v.URL, v.Context, "--url", "JENKINS_URL"))
+	printField(w, "Username", describeSetting(v.Username, v.Context, "--user", "JENKINS_USER"))
+	printField(w, "Token", describeSetting(v.Token, v.Context, "", "JENKINS_TOKEN"))
+	if v.AllowWrite {
+		printField(w, "Write Access", "enabled")
+	} else {
+		printField(w, "Write Access", "disabled")
+	}
+	if v.CAFile != "" {
+		printField(w, "CA File", v.CAFile)
+	}
+	if v.ClientCert != "" {
+		printField(w, "Client Cert", v.ClientCert)
+	}
+	if v.InsecureSkipVerify {
+		printField(w, "TLS Verification", "disabled (insecure)")
+	}
+	if v.Timeout != "" {
+		printField(w, "Timeout", v.Timeout)
+	}
+	if v.Proxy != "" {
+		printField(w, "Proxy", v.Proxy)
+	}
+	if v.Retries != nil {
+		printField(w, "Retries", *v.Retries)
+	}
+}
+
+func (r *whoAmI) writeText(w io.Writer) {
+	r.configView.writeText(w)
+	if r.Anonymous || !r.Authenticated {
+		printField(w, "Authenticated As", "anonymous (the token was not accepted)")
+	} else {
+		printField(w, "Authenticated As", r.Name)
+	}
+	if len(r.Authorities) > 0 {
+		printField(w, "Authorities", strings.Join(r.Authorities, ", "))
+	}
+}
+
+// describeSetting formats a setting with where it came from, e.g. "alice (env JENKINS_USER)"
+func describeSetting(s configSetting, contextName, flagName, envName string) string {
+	var source string
+	switch config.Source(s.Source) {
+	case config.SourceFlag:
+		source = "flag " + flagName
+	case config.SourceEnv:
+		source = "env " + envName
+	case config.SourceConfig:
+		source = fmt.Sprintf("context %q", contextName)
+	case config.SourceNetrc:
+		source = "netrc file"
+	case config.SourceFile:
+		source = "encrypted token file"
+	case config.SourceCommand:
+		source = "token command"
+	case "":
+		return "(not set)"
+	default:
+		source = s.Source
+	}
+	return fmt.Sprintf("%s (%s)", s.Value, source)
+}
+
+// newConfigView describes resolved credentials, masking the token
+func newConfigView(creds *config.Credentials) configView {
+	return configView{
+		Context:            creds.Context,
+		URL:                configSetting{Value: creds.URL, Source: string(creds.URLSource)},
+		Username:           configSetting{Value: creds.Username, Source: string(creds.UsernameSource)},
+		Token:              configSetting{Value: maskToken(creds.Token), Source: string(creds.TokenSource)},
+		AllowWrite:         creds.AllowWrite || allowWrite,
+		CAFile:             creds.TLS.CAFile,
+		ClientCert:         creds.TLS.ClientCert,
+		InsecureSkipVerify: creds.TLS.InsecureSkipVerify,
+		Timeout:            creds.HTTP.Timeout,
+		Proxy:              creds.HTTP.Proxy,
+		Retries:            creds.HTTP.Retries,
+	}
+}
+
+// maskToken hides all but the last 4 characters of a token, or all of a short one
+func maskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", 8) + token[len(token)-4:]
+}
+
+// fetchWhoAmI asks Jenkins who the client's credentials authenticate as
+func fetchWhoAmI(ctx context.Context, client *gojenkins.Jenkins) (*whoAmI, error) {
+	var resp struct {
+		Name          string   `json:"name"`
+		Authenticated bool     `json:"authenticated"`
+		Anonymous     bool     `json:"anonymous"`
+		Authorities   []string `json:"authorities"`
+	}
+	if err := getJSON(ctx, client, "/whoAmI", &resp, nil); err != nil {
+		return nil, fmt.Errorf("failed to get the authenticated user: %w", err)
+	}
+	result := &whoAmI{
+		Name:          resp.Name,
+		Authenticated: resp.Authenticated,
+		Anonymous:     resp.Anonymous,
+		Authorities:   resp.Authorities,
+	}
+	if result.Authorities == nil {
+		result.Authorities = []string{}
+	}
+	return result, nil
+}
+
+// viewConfig prints the resolved configuration and where each setting came from, without contacting Jenkins
+func viewConfig(p printer) error {
+	creds, err := resolveCredentials()
+	if err != nil {
+		return err
+	}
+	view := newConfigView(creds)
+	return p(os.Stdout, &view)
+}
+
+// printWhoAmI prints the resolved configuration and the user Jenkins authenticates it as
+func printWhoAmI(ctx context.Context, p printer) error {
+	creds, err := resolveCredentials()
+	if err != nil {
+		return err
+	}
+	client, err := connect(ctx, creds)
+	if err != nil {
+		return err
+	}
+	result, err := fetchWhoAmI(ctx, client)
+	if err != nil {
+		return err
+	}
+	result.configView = newConfigView(creds)
+	return p(os.Stdout, result)
+}
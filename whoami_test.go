@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/jenkins-cli/internal/config"
+)
+
+// TestFetchWhoAmI tests asking Jenkins who the credentials authenticate as
+func TestFetchWhoAmI(t *testing.T) {
+	client := newTestJenkins(t, map[string]http.HandlerFunc{
+		"/whoAmI/api/json/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"alice","authenticated":true,"anonymous":false,"authorities":["authenticated","devs"]}`))
+		},
+	})
+
+	result, err := fetchWhoAmI(context.Background(), client)
+	if err != nil {
+		t.Fatalf("fetchWhoAmI returned error: %v", err)
+	}
+	if result.Name != "alice" || !result.Authenticated || len(result.Authorities) != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+// TestConfigView tests that each setting is shown with where it came from, and the token is masked
+func TestConfigView(t *testing.T) {
+	creds := &config.Credentials{
+		Context:        "prod",
+		URL:            "https://jenkins.example.com",
+		URLSource:      config.SourceConfig,
+		Username:       "alice",
+		UsernameSource: config.SourceEnv,
+		Token:          "11aa22bb33cc44dd",
+		TokenSource:    config.SourceKeyring,
+		TLS:            config.TLS{CAFile: "/etc/ssl/corp-ca.pem"},
+	}
+	view := newConfigView(creds)
+
+	var buf bytes.Buffer
+	view.writeText(&buf)
+	for _, want := range []string{
+		`https://jenkins.example.com (context "prod")`,
+		"alice (env JENKINS_USER)",
+		"********44dd (keyring)",
+		"/etc/ssl/corp-ca.pem",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "11aa22bb") {
+		t.Errorf("Expected the token to be masked:\n%s", buf.String())
+	}
+}